@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the account server. Values are
+// resolved in order from the `default` tag, the optional file named by
+// CONFIG_FILE (.yaml, .yml or .toml) and finally the environment
+// variable named by the `env` tag. The `desc` tags document .env.dev,
+// see WriteEnvTemplate
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Postgres  Postgres  `yaml:"postgres" toml:"postgres"`
+	Redis     Redis     `yaml:"redis" toml:"redis"`
+	Token     Token     `yaml:"token" toml:"token"`
+	Storage   Storage   `yaml:"storage" toml:"storage"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Lockout   Lockout   `yaml:"lockout" toml:"lockout"`
+	Hash      Hash      `yaml:"hash" toml:"hash"`
+	Mail      Mail      `yaml:"mail" toml:"mail"`
+	OIDC      OIDC      `yaml:"oidc" toml:"oidc"`
+}
+
+type Server struct {
+	Addr                string `env:"SERVER_ADDR" default:":8080" yaml:"addr" toml:"addr" desc:"Address the API listens on"`
+	MetricsAddr         string `env:"METRICS_ADDR" default:":9090" yaml:"metrics_addr" toml:"metrics_addr" desc:"Internal address serving Prometheus metrics on /metrics, never route it publicly"`
+	AuthAPIURL          string `env:"AUTH_API_URL" yaml:"auth_api_url" toml:"auth_api_url" desc:"Path prefix of every account route, eg. /api/account"`
+	StartupTimeoutSecs  int64  `env:"STARTUP_TIMEOUT_SECS" default:"30" yaml:"startup_timeout_secs" toml:"startup_timeout_secs" desc:"Seconds allowed for connecting to data sources and migrating"`
+	ShutdownTimeoutSecs int64  `env:"SHUTDOWN_TIMEOUT_SECS" default:"5" yaml:"shutdown_timeout_secs" toml:"shutdown_timeout_secs" desc:"Seconds in-flight requests get to finish on shutdown"`
+	DrainSecs           int64  `env:"SHUTDOWN_DRAIN_SECS" default:"5" yaml:"drain_secs" toml:"drain_secs" desc:"Seconds /readyz reports draining before shutdown starts, so the proxy stops routing traffic"`
+	ReadyTimeoutMillis  int64  `env:"READY_CHECK_TIMEOUT_MS" default:"2000" yaml:"ready_timeout_ms" toml:"ready_timeout_ms" desc:"Milliseconds each dependency gets to answer a /readyz check"`
+	MaxBodyBytes        int64  `env:"HANDLER_MAX_BODY_BYTES" default:"4194304" yaml:"max_body_bytes" toml:"max_body_bytes" desc:"Largest accepted profile image upload in bytes"`
+	TrustedProxies      string `env:"TRUSTED_PROXIES" yaml:"trusted_proxies" toml:"trusted_proxies" desc:"Comma separated proxy IPs or CIDRs whose X-Forwarded-For is believed for client IPs, eg. the address of Traefik, none when empty"`
+	LogLevel            string `env:"LOG_LEVEL" default:"info" yaml:"log_level" toml:"log_level" desc:"Minimum level logged, one of debug, info, warn or error"`
+}
+
+type RateLimit struct {
+	IPBurst         int64 `env:"RATE_LIMIT_IP_BURST" default:"20" yaml:"ip_burst" toml:"ip_burst" desc:"Sign up, sign in and token requests a client IP can make in a row, per route"`
+	IPRefillSecs    int64 `env:"RATE_LIMIT_IP_REFILL_SECS" default:"3" yaml:"ip_refill_secs" toml:"ip_refill_secs" desc:"Seconds for a client IP to regain one request"`
+	EmailBurst      int64 `env:"RATE_LIMIT_EMAIL_BURST" default:"5" yaml:"email_burst" toml:"email_burst" desc:"Sign in attempts an email can make in a row"`
+	EmailRefillSecs int64 `env:"RATE_LIMIT_EMAIL_REFILL_SECS" default:"60" yaml:"email_refill_secs" toml:"email_refill_secs" desc:"Seconds for an email to regain one sign in attempt"`
+}
+
+type Lockout struct {
+	Threshold int64 `env:"LOCKOUT_THRESHOLD" default:"5" yaml:"threshold" toml:"threshold" desc:"Consecutive failed sign ins locking an account, 0 disables lockout"`
+	BaseSecs  int64 `env:"LOCKOUT_BASE_SECS" default:"60" yaml:"base_secs" toml:"base_secs" desc:"Seconds of the first lockout, doubled by every further failed sign in"`
+	MaxSecs   int64 `env:"LOCKOUT_MAX_SECS" default:"3600" yaml:"max_secs" toml:"max_secs" desc:"Longest lockout in seconds"`
+}
+
+type Hash struct {
+	ScryptN int64 `env:"SCRYPT_N" default:"32768" yaml:"scrypt_n" toml:"scrypt_n" desc:"scrypt CPU/memory cost of password hashes, a power of two. Raising a cost rehashes passwords at their next sign in"`
+	ScryptR int64 `env:"SCRYPT_R" default:"8" yaml:"scrypt_r" toml:"scrypt_r" desc:"scrypt block size of password hashes"`
+	ScryptP int64 `env:"SCRYPT_P" default:"1" yaml:"scrypt_p" toml:"scrypt_p" desc:"scrypt parallelization of password hashes"`
+}
+
+type Mail struct {
+	Mailer       string `env:"MAILER" default:"file" yaml:"mailer" toml:"mailer" desc:"How emails are delivered, smtp or file"`
+	Dir          string `env:"MAIL_DIR" default:"./mail" yaml:"dir" toml:"dir" desc:"Directory the file mailer writes .eml files to"`
+	From         string `env:"MAIL_FROM" default:"Remember <no-reply@remember.test>" yaml:"from" toml:"from" desc:"Sender of every email"`
+	SMTPHost     string `env:"SMTP_HOST" yaml:"smtp_host" toml:"smtp_host" desc:"SMTP server host"`
+	SMTPPort     string `env:"SMTP_PORT" default:"587" yaml:"smtp_port" toml:"smtp_port" desc:"SMTP server port"`
+	SMTPUser     string `env:"SMTP_USER" yaml:"smtp_user" toml:"smtp_user" desc:"SMTP username, no authentication when empty"`
+	SMTPPassword string `env:"SMTP_PASSWORD" yaml:"smtp_password" toml:"smtp_password" desc:"SMTP password"`
+	VerifyURL    string `env:"VERIFY_EMAIL_URL" default:"http://dev2000.test/api/account/verify-email" yaml:"verify_url" toml:"verify_url" desc:"Link in verification emails, the token is added as the token query parameter"`
+	ResetURL     string `env:"RESET_PASSWORD_URL" default:"http://dev2000.test/reset-password" yaml:"reset_url" toml:"reset_url" desc:"Page of password reset emails posting the token and a new password to /password/reset, the token is added as the token query parameter"`
+}
+
+type OIDC struct {
+	Provider            string `env:"OIDC_PROVIDER" default:"oidc" yaml:"provider" toml:"provider" desc:"Name of the identity provider in the /oidc/<provider>/login and /oidc/<provider>/callback routes"`
+	Issuer              string `env:"OIDC_ISSUER" yaml:"issuer" toml:"issuer" desc:"OpenID Connect issuer URL of the identity provider, sign in with it is disabled when empty"`
+	ClientID            string `env:"OIDC_CLIENT_ID" yaml:"client_id" toml:"client_id" desc:"Client ID registered with the identity provider"`
+	ClientSecret        string `env:"OIDC_CLIENT_SECRET" yaml:"client_secret" toml:"client_secret" desc:"Client secret registered with the identity provider"`
+	RedirectURL         string `env:"OIDC_REDIRECT_URL" yaml:"redirect_url" toml:"redirect_url" desc:"Public URL of the callback route registered with the identity provider, eg. http://dev2000.test/api/account/oidc/oidc/callback"`
+	Scopes              string `env:"OIDC_SCOPES" default:"openid email profile" yaml:"scopes" toml:"scopes" desc:"Space separated scopes requested from the identity provider"`
+	StateExpirationSecs int64  `env:"OIDC_STATE_EXP" default:"600" yaml:"state_exp" toml:"state_exp" desc:"Seconds a user has to sign in at the identity provider"`
+}
+
+type Postgres struct {
+	Host     string `env:"PG_HOST" required:"true" yaml:"host" toml:"host" desc:"Postgres host"`
+	Port     string `env:"PG_PORT" default:"5432" yaml:"port" toml:"port" desc:"Postgres port"`
+	User     string `env:"PG_USER" required:"true" yaml:"user" toml:"user" desc:"Postgres user"`
+	Password string `env:"PG_PASSWORD" yaml:"password" toml:"password" desc:"Postgres password"`
+	DB       string `env:"PG_DB" required:"true" yaml:"db" toml:"db" desc:"Postgres database name"`
+	SSL      string `env:"PG_SSL" default:"disable" yaml:"ssl" toml:"ssl" desc:"Postgres sslmode"`
+}
+
+type Redis struct {
+	Host string `env:"REDIS_HOST" required:"true" yaml:"host" toml:"host" desc:"Redis host"`
+	Port string `env:"REDIS_PORT" default:"6379" yaml:"port" toml:"port" desc:"Redis port"`
+}
+
+type Token struct {
+	PrivKeyFile             string `env:"PRIV_KEY_FILE" required:"true" yaml:"priv_key_file" toml:"priv_key_file" desc:"PEM file of the RSA key signing ID tokens, see make create-keypair"`
+	PubKeyFile              string `env:"PUB_KEY_FILE" required:"true" yaml:"pub_key_file" toml:"pub_key_file" desc:"PEM file of the RSA key verifying ID tokens"`
+	RefreshSecret           string `env:"REFRESH_SECRET" required:"true" yaml:"refresh_secret" toml:"refresh_secret" desc:"Secret signing refresh tokens"`
+	IDExpirationSecs        int64  `env:"ID_TOKEN_EXP" default:"900" yaml:"id_token_exp" toml:"id_token_exp" desc:"ID token lifetime in seconds"`
+	RefreshExpirationSecs   int64  `env:"REFRESH_TOKEN_EXP" default:"259200" yaml:"refresh_token_exp" toml:"refresh_token_exp" desc:"Refresh token lifetime in seconds"`
+	VerifySecret            string `env:"VERIFY_EMAIL_SECRET" required:"true" yaml:"verify_secret" toml:"verify_secret" desc:"Secret signing email verification tokens, different from REFRESH_SECRET"`
+	VerifyExpirationSecs    int64  `env:"VERIFY_EMAIL_EXP" default:"86400" yaml:"verify_exp" toml:"verify_exp" desc:"Email verification link lifetime in seconds"`
+	ResetExpirationSecs     int64  `env:"RESET_PASSWORD_EXP" default:"3600" yaml:"reset_exp" toml:"reset_exp" desc:"Password reset link lifetime in seconds"`
+	ChallengeSecret         string `env:"TWO_FACTOR_SECRET" required:"true" yaml:"challenge_secret" toml:"challenge_secret" desc:"Secret signing two-factor sign in challenges, different from the other secrets"`
+	ChallengeExpirationSecs int64  `env:"TWO_FACTOR_CHALLENGE_EXP" default:"300" yaml:"challenge_exp" toml:"challenge_exp" desc:"Seconds a two-factor user has to enter their code after their password"`
+	TOTPIssuer              string `env:"TOTP_ISSUER" default:"Remember" yaml:"totp_issuer" toml:"totp_issuer" desc:"Name of the service in authenticator apps"`
+}
+
+type Storage struct {
+	ImageStorage string `env:"IMAGE_STORAGE" default:"local" yaml:"image_storage" toml:"image_storage" desc:"Where profile images are stored, local or s3"`
+	ImageDir     string `env:"IMAGE_DIR" default:"./images" yaml:"image_dir" toml:"image_dir" desc:"Directory the local image storage keeps profile images in"`
+	ImageURL     string `env:"IMAGE_URL" required:"true" yaml:"image_url" toml:"image_url" desc:"URL profile images are served under, the path the server serves local images on, eg. /api/account/images, or the public URL of the s3 bucket"`
+	S3Endpoint   string `env:"S3_ENDPOINT" yaml:"s3_endpoint" toml:"s3_endpoint" desc:"host:port of the S3 compatible storage, eg. s3.amazonaws.com or minio-remember:9000"`
+	S3Region     string `env:"S3_REGION" default:"us-east-1" yaml:"s3_region" toml:"s3_region" desc:"Region of the s3 bucket"`
+	S3Bucket     string `env:"S3_BUCKET" default:"profile-images" yaml:"s3_bucket" toml:"s3_bucket" desc:"Bucket profile images are stored in, created publicly readable when missing"`
+	S3AccessKey  string `env:"S3_ACCESS_KEY" yaml:"s3_access_key" toml:"s3_access_key" desc:"Access key of the s3 storage"`
+	S3SecretKey  string `env:"S3_SECRET_KEY" yaml:"s3_secret_key" toml:"s3_secret_key" desc:"Secret key of the s3 storage"`
+	S3UseSSL     bool   `env:"S3_USE_SSL" default:"true" yaml:"s3_use_ssl" toml:"s3_use_ssl" desc:"Whether the s3 storage is reached over HTTPS"`
+}
+
+// Load builds the Config from defaults, CONFIG_FILE and the environment
+// and reports every missing or malformed value at once
+func Load() (*Config, error) {
+	c := &Config{}
+
+	if err := walk(c, func(f field) error {
+		if def, ok := f.Tag.Lookup("default"); ok {
+			return f.set(def)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, c); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []string
+
+	walk(c, func(f field) error {
+		env := f.Tag.Get("env")
+
+		if val, ok := os.LookupEnv(env); ok {
+			if err := f.set(val); err != nil {
+				problems = append(problems, fmt.Sprintf("%v: %v", env, err))
+				return nil
+			}
+		}
+
+		if f.Tag.Get("required") == "true" && f.Value.IsZero() {
+			problems = append(problems, fmt.Sprintf("%v is required", env))
+		}
+
+		return nil
+	})
+
+	// a token signed with a shared secret would be accepted for another purpose
+	t := c.Token
+
+	if t.RefreshSecret != "" && (t.RefreshSecret == t.VerifySecret || t.RefreshSecret == t.ChallengeSecret) ||
+		t.VerifySecret != "" && t.VerifySecret == t.ChallengeSecret {
+		problems = append(problems, "REFRESH_SECRET, VERIFY_EMAIL_SECRET and TWO_FACTOR_SECRET must all differ")
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %v", strings.Join(problems, ", "))
+	}
+
+	return c, nil
+}
+
+// loadFile decodes a YAML or TOML file into c, chosen by its extension
+func loadFile(path string, c *Config) error {
+	contents, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(contents))
+		dec.KnownFields(true)
+
+		if err := dec.Decode(c); err != nil && err != io.EOF {
+			return fmt.Errorf("unable to parse config file %v: %w", path, err)
+		}
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(contents))
+		dec.DisallowUnknownFields()
+
+		if err := dec.Decode(c); err != nil {
+			return fmt.Errorf("unable to parse config file %v: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config file %v must be .yaml, .yml or .toml, got %q", path, ext)
+	}
+
+	return nil
+}
+
+// WriteEnvTemplate writes a .env file listing every setting with its
+// description and default value
+func WriteEnvTemplate(w io.Writer) error {
+	return walk(&Config{}, func(f field) error {
+		desc := f.Tag.Get("desc")
+
+		if f.Tag.Get("required") == "true" {
+			desc += " (required)"
+		}
+
+		_, err := fmt.Fprintf(w, "# %v\n%v=%v\n", desc, f.Tag.Get("env"), f.Tag.Get("default"))
+		return err
+	})
+}
+
+// field is a leaf setting of Config
+type field struct {
+	Tag   reflect.StructTag
+	Value reflect.Value
+}
+
+// set parses s into the field according to its kind
+func (f field) set(s string) error {
+	switch f.Value.Kind() {
+	case reflect.String:
+		f.Value.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, 64)
+
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+
+		f.Value.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+
+		f.Value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config kind %v", f.Value.Kind())
+	}
+
+	return nil
+}
+
+// walk calls fn for each leaf field of the sections of c
+func walk(c *Config, fn func(f field) error) error {
+	sections := reflect.ValueOf(c).Elem()
+
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+
+		for j := 0; j < section.NumField(); j++ {
+			f := field{
+				Tag:   section.Type().Field(j).Tag,
+				Value: section.Field(j),
+			}
+
+			if err := fn(f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
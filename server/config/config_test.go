@@ -0,0 +1,177 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setRequiredEnv sets every required value so Load can succeed
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("PG_HOST", "postgres-remember")
+	t.Setenv("PG_USER", "postgres")
+	t.Setenv("PG_DB", "postgres")
+	t.Setenv("REDIS_HOST", "redis-remember")
+	t.Setenv("PRIV_KEY_FILE", "./rsa_private_dev.pem")
+	t.Setenv("PUB_KEY_FILE", "./rsa_public_dev.pem")
+	t.Setenv("REFRESH_SECRET", "areallysecretsecret")
+	t.Setenv("VERIFY_EMAIL_SECRET", "anotherreallysecretsecret")
+	t.Setenv("TWO_FACTOR_SECRET", "yetanotherreallysecretsecret")
+	t.Setenv("IMAGE_URL", "/api/account/images")
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		c, err := Load()
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":8080", c.Server.Addr)
+		assert.Equal(t, int64(5), c.Server.ShutdownTimeoutSecs)
+		assert.Equal(t, int64(4194304), c.Server.MaxBodyBytes)
+		assert.Equal(t, "5432", c.Postgres.Port)
+		assert.Equal(t, "disable", c.Postgres.SSL)
+		assert.Equal(t, int64(900), c.Token.IDExpirationSecs)
+		assert.Equal(t, "postgres-remember", c.Postgres.Host)
+		assert.Empty(t, c.Server.TrustedProxies)
+	})
+
+	t.Run("Environment overrides defaults", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("SERVER_ADDR", ":9000")
+		t.Setenv("ID_TOKEN_EXP", "60")
+
+		c, err := Load()
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":9000", c.Server.Addr)
+		assert.Equal(t, int64(60), c.Token.IDExpirationSecs)
+	})
+
+	t.Run("Missing required values", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("PG_HOST", "")
+		os.Unsetenv("REFRESH_SECRET")
+
+		_, err := Load()
+
+		assert.EqualError(t, err, "invalid configuration: PG_HOST is required, REFRESH_SECRET is required")
+	})
+
+	t.Run("Shared secrets", func(t *testing.T) {
+		for _, env := range []string{"VERIFY_EMAIL_SECRET", "TWO_FACTOR_SECRET"} {
+			setRequiredEnv(t)
+			t.Setenv(env, "areallysecretsecret")
+
+			_, err := Load()
+
+			assert.EqualError(t, err, "invalid configuration: REFRESH_SECRET, VERIFY_EMAIL_SECRET and TWO_FACTOR_SECRET must all differ", env)
+		}
+
+		setRequiredEnv(t)
+		t.Setenv("TWO_FACTOR_SECRET", "anotherreallysecretsecret")
+
+		_, err := Load()
+
+		assert.EqualError(t, err, "invalid configuration: REFRESH_SECRET, VERIFY_EMAIL_SECRET and TWO_FACTOR_SECRET must all differ")
+	})
+
+	t.Run("Malformed integer", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("ID_TOKEN_EXP", "fifteen minutes")
+
+		_, err := Load()
+
+		assert.EqualError(t, err, `invalid configuration: ID_TOKEN_EXP: "fifteen minutes" is not an integer`)
+	})
+
+	t.Run("Booleans", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		c, err := Load()
+
+		assert.NoError(t, err)
+		assert.True(t, c.Storage.S3UseSSL)
+
+		t.Setenv("S3_USE_SSL", "false")
+
+		c, err = Load()
+
+		assert.NoError(t, err)
+		assert.False(t, c.Storage.S3UseSSL)
+
+		t.Setenv("S3_USE_SSL", "sometimes")
+
+		_, err = Load()
+
+		assert.EqualError(t, err, `invalid configuration: S3_USE_SSL: "sometimes" is not a boolean`)
+	})
+
+	t.Run("YAML file", func(t *testing.T) {
+		setRequiredEnv(t)
+		// the environment wins over the file
+		t.Setenv("PG_HOST", "from-env")
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  addr: ":7000"
+postgres:
+  host: from-file
+  port: "5433"
+`), 0600))
+		t.Setenv("CONFIG_FILE", path)
+
+		c, err := Load()
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":7000", c.Server.Addr)
+		assert.Equal(t, "5433", c.Postgres.Port)
+		assert.Equal(t, "from-env", c.Postgres.Host)
+	})
+
+	t.Run("TOML file", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		path := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+[token]
+id_token_exp = 120
+refresh_token_exp = 3600
+`), 0600))
+		t.Setenv("CONFIG_FILE", path)
+
+		c, err := Load()
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(120), c.Token.IDExpirationSecs)
+		assert.Equal(t, int64(3600), c.Token.RefreshExpirationSecs)
+	})
+
+	t.Run("Unknown file key", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("server:\n  adr: \":7000\"\n"), 0600))
+		t.Setenv("CONFIG_FILE", path)
+
+		_, err := Load()
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWriteEnvTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	assert.NoError(t, WriteEnvTemplate(buf))
+	assert.Contains(t, buf.String(), "# Postgres host (required)\nPG_HOST=\n")
+	assert.Contains(t, buf.String(), "# Address the API listens on\nSERVER_ADDR=:8080\n")
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/redis/go-redis/v9"
+	"github.com/vuluu2k/remember_fullstack/server/config"
+	"github.com/vuluu2k/remember_fullstack/server/health"
+	"github.com/vuluu2k/remember_fullstack/server/repository"
+)
+
+type dataSources struct {
+	DB          *sqlx.DB
+	RedisClient *redis.Client
+	// exactly one of ImageDir and S3Client is set, by IMAGE_STORAGE
+	ImageDir string
+	S3Client *minio.Client
+	S3Bucket string
+}
+
+// initDS establishes connections to fields in dataSources
+// and brings the database schema up to date. Sources opened
+// before a failing one are closed again
+func initDS(ctx context.Context, cfg *config.Config) (*dataSources, error) {
+	slog.Info("Initializing data sources")
+
+	d := &dataSources{}
+
+	if err := d.open(ctx, cfg); err != nil {
+		if closeErr := d.close(); closeErr != nil {
+			slog.Error("Unable to close data sources after failed initialization", "err", closeErr)
+		}
+
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *dataSources) open(ctx context.Context, cfg *config.Config) error {
+	pg := cfg.Postgres
+	pgConnString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", pg.Host, pg.Port, pg.User, pg.Password, pg.DB, pg.SSL)
+
+	slog.Info("Connecting to Postgresql")
+	db, err := sqlx.Open("postgres", pgConnString)
+
+	if err != nil {
+		return fmt.Errorf("error opening db: %w", err)
+	}
+
+	d.DB = db
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("error connecting to db: %w", err)
+	}
+
+	slog.Info("Migrating database schema")
+
+	if err := repository.Migrate(ctx, db); err != nil {
+		return fmt.Errorf("error migrating db: %w", err)
+	}
+
+	// Initialize redis connection
+	slog.Info("Connecting to Redis")
+	d.RedisClient = redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
+		Password: "",
+		DB:       0,
+	})
+
+	// verify redis connection
+	if _, err := d.RedisClient.Ping(ctx).Result(); err != nil {
+		return fmt.Errorf("error connecting to redis: %w", err)
+	}
+
+	slog.Info("Preparing image storage")
+
+	return d.openImageStorage(ctx, &cfg.Storage)
+}
+
+// openImageStorage prepares the image storage named by s.ImageStorage
+func (d *dataSources) openImageStorage(ctx context.Context, s *config.Storage) error {
+	switch s.ImageStorage {
+	case "local":
+		if s.ImageDir == "" {
+			return errors.New("IMAGE_DIR is required by the local image storage")
+		}
+
+		if err := os.MkdirAll(s.ImageDir, 0755); err != nil {
+			return fmt.Errorf("error creating image directory: %w", err)
+		}
+
+		d.ImageDir = s.ImageDir
+	case "s3":
+		if s.S3Endpoint == "" || s.S3Bucket == "" {
+			return errors.New("S3_ENDPOINT and S3_BUCKET are required by the s3 image storage")
+		}
+
+		client, err := minio.New(s.S3Endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(s.S3AccessKey, s.S3SecretKey, ""),
+			Secure: s.S3UseSSL,
+			Region: s.S3Region,
+		})
+
+		if err != nil {
+			return fmt.Errorf("error creating s3 client: %w", err)
+		}
+
+		if err := repository.PrepareImageBucket(ctx, client, s.S3Bucket, s.S3Region); err != nil {
+			return fmt.Errorf("error preparing s3 bucket: %w", err)
+		}
+
+		d.S3Client = client
+		d.S3Bucket = s.S3Bucket
+	default:
+		return fmt.Errorf("unknown image storage %q, expected local or s3", s.ImageStorage)
+	}
+
+	return nil
+}
+
+// close to be used in graceful server shutdown. Sources are closed
+// in the reverse order they were opened in, all of them are closed
+// even if one fails
+func (d *dataSources) close() error {
+	var errs []error
+
+	if d.RedisClient != nil {
+		slog.Info("Closing Redis Client")
+
+		if err := d.RedisClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing Redis Client: %w", err))
+		}
+	}
+
+	if d.DB != nil {
+		slog.Info("Closing Postgresql")
+
+		if err := d.DB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing Postgresql: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checks probe every data source for the readiness endpoint
+func (d *dataSources) checks() map[string]health.Check {
+	return map[string]health.Check{
+		"postgres": func(ctx context.Context) error {
+			return d.DB.PingContext(ctx)
+		},
+		"redis": func(ctx context.Context) error {
+			return d.RedisClient.Ping(ctx).Err()
+		},
+		"storage": d.checkImageStorage,
+	}
+}
+
+// checkImageStorage makes sure images can still be stored
+func (d *dataSources) checkImageStorage(ctx context.Context) error {
+	if d.S3Client != nil {
+		exists, err := d.S3Client.BucketExists(ctx, d.S3Bucket)
+
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return fmt.Errorf("bucket %s does not exist", d.S3Bucket)
+		}
+
+		return nil
+	}
+
+	info, err := os.Stat(d.ImageDir)
+
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", d.ImageDir)
+	}
+
+	return nil
+}
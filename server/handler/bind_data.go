@@ -1,10 +1,9 @@
 package handler
 
 import (
-	"log"
-
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
 	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
 )
 
@@ -18,12 +17,13 @@ type invalidArgument struct {
 func bindData(c *gin.Context, req interface{}) bool {
 
 	if err := c.ShouldBind(req); err != nil {
-		log.Printf("Error binding data: %v\n", err)
-
 		if errs, ok := err.(validator.ValidationErrors); ok {
 			var invalidArgs []invalidArgument
 
 			for _, err := range errs {
+				// values are left out as they may hold passwords
+				logging.FromContext(c).Info("Invalid request parameter", "field", err.Field(), "tag", err.Tag())
+
 				invalidArgs = append(invalidArgs, invalidArgument{
 					Field: err.Field(),
 					Value: err.Value().(string),
@@ -42,6 +42,8 @@ func bindData(c *gin.Context, req interface{}) bool {
 			return false
 		}
 
+		logging.FromContext(c).Info("Unable to bind request data", "err", err)
+
 		fallback := apperrors.NewInternal()
 
 		c.JSON(fallback.Status(), gin.H{
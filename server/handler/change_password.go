@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// changePasswordReq takes new passwords like signUpReq. Reusing the
+// current password fails nefield like any other invalid argument
+type changePasswordReq struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,gte=6,lte=30,nefield=CurrentPassword"`
+}
+
+// ChangePassword handler sets a new password for the signed in user.
+// Every other session is signed out and the caller gets fresh tokens
+func (h *Handler) ChangePassword(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	var req changePasswordReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	uid := user.(*model.User).UID
+
+	u, err := h.UserService.ChangePassword(c, uid, req.CurrentPassword, req.NewPassword)
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to change password", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if err := h.TokenService.Signout(c, uid); err != nil {
+		logging.FromContext(c).Error("Failed to sign out sessions after password change", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(c, u, "")
+
+	if err != nil {
+		logging.FromContext(c).Error("Failed to create tokens after password change", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
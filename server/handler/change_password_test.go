@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestChangePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	put := func(router *gin.Engine, t *testing.T, reqBody gin.H) *http.Response {
+		rr := newRecorder(t)
+
+		body, err := json.Marshal(reqBody)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(body))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		return rr.Result()
+	}
+
+	t.Run("Data binding error", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		for _, reqBody := range []gin.H{
+			{"newPassword": "newpassword"},
+			{"currentPassword": "currentpassword"},
+			{"currentPassword": "currentpassword", "newPassword": "short"},
+		} {
+			res := put(router, t, reqBody)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		}
+
+		mockUserService.AssertNotCalled(t, "ChangePassword")
+	})
+
+	t.Run("Reusing the current password", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		res := put(router, t, gin.H{
+			"currentPassword": "samepassword",
+			"newPassword":     "samepassword",
+		})
+
+		var body struct {
+			InvalidArgs []invalidArgument `json:"invalidArgs"`
+		}
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Len(t, body.InvalidArgs, 1)
+		assert.Equal(t, "NewPassword", body.InvalidArgs[0].Field)
+		assert.Equal(t, "nefield", body.InvalidArgs[0].Tag)
+		assert.Equal(t, "CurrentPassword", body.InvalidArgs[0].Param)
+		mockUserService.AssertNotCalled(t, "ChangePassword")
+	})
+
+	t.Run("Success signs out other sessions", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		u := &model.User{UID: uid, Email: "vuluu040320@gmail.com"}
+		tokens := &model.TokenPair{TokenID: "idToken", RefreshToken: "refreshToken"}
+
+		mockUserService.On("ChangePassword", mock.AnythingOfType("*gin.Context"), uid, "currentpassword", "newpassword").Return(u, nil)
+		signout := mockTokenService.On("Signout", mock.AnythingOfType("*gin.Context"), uid).Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*gin.Context"), u, "").Return(tokens, nil).NotBefore(signout)
+
+		res := put(router, t, gin.H{
+			"currentPassword": "currentpassword",
+			"newPassword":     "newpassword",
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": tokens,
+		})
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(res.Body)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, respBody, buf.Bytes())
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		mockErr := apperrors.NewAuthorization("Current password is incorrect")
+		mockUserService.On("ChangePassword", mock.AnythingOfType("*gin.Context"), uid, "wrongpassword", "newpassword").Return(nil, mockErr)
+
+		res := put(router, t, gin.H{
+			"currentPassword": "wrongpassword",
+			"newPassword":     "newpassword",
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		mockTokenService.AssertNotCalled(t, "Signout")
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Rate limited per user", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+
+		ipLimit := model.RateLimit{Burst: 20, Interval: time.Second}
+		userLimit := model.RateLimit{Burst: 5, Interval: time.Minute}
+
+		mockRateLimitRepository.On("Take", mock.Anything, mock.MatchedBy(func(key string) bool {
+			return strings.HasPrefix(key, "password-change:ip:")
+		}), ipLimit).Return(time.Duration(0), nil)
+		mockRateLimitRepository.On("Take", mock.Anything, "password-change:user:"+uid.String(), userLimit).Return(time.Minute, nil)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                   router,
+			UserService:         mockUserService,
+			TokenService:        mockTokenService,
+			RateLimitRepository: mockRateLimitRepository,
+			IPRateLimit:         ipLimit,
+			EmailRateLimit:      userLimit,
+		})
+
+		res := put(router, t, gin.H{
+			"currentPassword": "wrongpassword",
+			"newPassword":     "newpassword",
+		})
+
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		mockRateLimitRepository.AssertExpectations(t)
+		mockUserService.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// omitempty must be listed first as tags are evaluated in order
+type detailsReq struct {
+	Name    string `json:"name" binding:"omitempty,max=50"`
+	Email   string `json:"email" binding:"required,email"`
+	Website string `json:"website" binding:"omitempty,url"`
+}
+
+// Details handler updates the signed in user's profile details
+func (h *Handler) Details(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	var req detailsReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u := &model.User{
+		UID:     user.(*model.User).UID,
+		Name:    req.Name,
+		Email:   req.Email,
+		Website: req.Website,
+	}
+
+	emailChanged, err := h.UserService.UpdateDetails(c, u)
+
+	if err != nil {
+		logging.FromContext(c).Error("Failed to update user", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// a new email is unverified until the link sent to it is opened, the
+	// details are saved either way
+	if emailChanged {
+		if err := h.VerificationService.SendVerification(c, u); err != nil {
+			logging.FromContext(c).Error("Failed to send verification email", "uid", u.UID, "err", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": u.Public(),
+	})
+}
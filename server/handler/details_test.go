@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID:   uid,
+		Email: "vuluu040320@gmail.com",
+	}
+
+	mockTokenService := new(mocks.MockTokenService)
+	router := authedRouter(mockTokenService, ctxUser)
+
+	mockUserService := new(mocks.MockUserService)
+	mockVerificationService := new(mocks.MockVerificationService)
+
+	NewHandler(&Config{
+		R:                   router,
+		UserService:         mockUserService,
+		TokenService:        mockTokenService,
+		VerificationService: mockVerificationService,
+	})
+
+	t.Run("Data binding error", func(t *testing.T) {
+		for _, reqBody := range []gin.H{
+			{"email": "notanemail"},
+			{"website": "https://remember.test"},
+			{"email": "vuluu040320@gmail.com", "website": "notawebsite"},
+			{"email": "vuluu040320@gmail.com", "name": "Lorem ipsum dolor sit amet consectetur adipiscing elit"},
+		} {
+			rr := newRecorder(t)
+
+			body, _ := json.Marshal(reqBody)
+
+			request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(body))
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+
+		mockUserService.AssertNotCalled(t, "UpdateDetails")
+	})
+
+	t.Run("Update success", func(t *testing.T) {
+		rr := newRecorder(t)
+
+		newName := "Vũ Lưu"
+		newEmail := "vuluu040320@gmail.com"
+		newWebsite := "https://remember.test"
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":    newName,
+			"email":   newEmail,
+			"website": newWebsite,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:     ctxUser.UID,
+			Name:    newName,
+			Email:   newEmail,
+			Website: newWebsite,
+		}
+
+		updateArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			userToUpdate,
+		}
+
+		dbImageURL := "/images/avatar.png"
+
+		mockUserService.
+			On("UpdateDetails", updateArgs...).
+			Run(func(args mock.Arguments) {
+				userArg := args.Get(1).(*model.User)
+				userArg.ImageUrl = dbImageURL
+			}).
+			Return(false, nil)
+
+		router.ServeHTTP(rr, request)
+
+		userToUpdate.ImageUrl = dbImageURL
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": userToUpdate.Public(),
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertCalled(t, "UpdateDetails", updateArgs...)
+
+		// the email didn't change
+		mockVerificationService.AssertNotCalled(t, "SendVerification", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Changed email is sent a verification link", func(t *testing.T) {
+		rr := newRecorder(t)
+
+		newEmail := "new@remember.test"
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": newEmail,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:   ctxUser.UID,
+			Email: newEmail,
+		}
+
+		mockUserService.On("UpdateDetails", mock.AnythingOfType("*gin.Context"), userToUpdate).Return(true, nil)
+
+		// the details are saved even if the email can't be sent
+		mockVerificationService.
+			On("SendVerification", mock.AnythingOfType("*gin.Context"), userToUpdate).
+			Return(apperrors.NewInternal())
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockVerificationService.AssertExpectations(t)
+	})
+
+	t.Run("Email in a stale ID token", func(t *testing.T) {
+		rr := newRecorder(t)
+
+		// stored since the ID token was issued
+		storedEmail := "stored@remember.test"
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": storedEmail,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:   ctxUser.UID,
+			Email: storedEmail,
+		}
+
+		mockUserService.On("UpdateDetails", mock.AnythingOfType("*gin.Context"), userToUpdate).Return(false, nil)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockVerificationService.AssertNotCalled(t, "SendVerification", mock.Anything, userToUpdate)
+	})
+
+	t.Run("Update failure", func(t *testing.T) {
+		rr := newRecorder(t)
+
+		newName := "Vũ Lưu"
+		newEmail := "taken@remember.test"
+		newWebsite := "https://remember.test"
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":    newName,
+			"email":   newEmail,
+			"website": newWebsite,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:     ctxUser.UID,
+			Name:    newName,
+			Email:   newEmail,
+			Website: newWebsite,
+		}
+
+		updateArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			userToUpdate,
+		}
+
+		mockError := apperrors.NewConflict("email", newEmail)
+
+		mockUserService.
+			On("UpdateDetails", updateArgs...).
+			Return(false, mockError)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertCalled(t, "UpdateDetails", updateArgs...)
+	})
+}
@@ -1,73 +1,105 @@
 package handler
 
 import (
-	"net/http"
-	"os"
-
 	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/handler/middleware"
 	"github.com/vuluu2k/remember_fullstack/server/model"
 )
 
 type Handler struct {
-	UserService  model.UserService
-	TokenService model.TokenService
+	UserService         model.UserService
+	TokenService        model.TokenService
+	VerificationService model.VerificationService
+	PasswordService     model.PasswordService
+	TwoFactorService    model.TwoFactorService
+	OIDCService         model.OIDCService
+	MaxBodyBytes        int64
 }
 
 type Config struct {
 	R            *gin.Engine
 	UserService  model.UserService
 	TokenService model.TokenService
+	// VerificationService confirms emails of users who signed up
+	VerificationService model.VerificationService
+	// PasswordService resets forgotten passwords
+	PasswordService model.PasswordService
+	// TwoFactorService enrolls authenticators and checks their codes
+	TwoFactorService model.TwoFactorService
+	// OIDCService signs users in with identity providers, its routes
+	// are left out when it is nil
+	OIDCService model.OIDCService
+	// BaseURL prefixes every route of the handler
+	BaseURL string
+	// MaxBodyBytes limits the size of uploaded profile images
+	MaxBodyBytes int64
+	// RateLimitRepository keeps the rate limits of the sign up, sign in,
+	// two-factor, OIDC, token, password and verification resend routes,
+	// which are not limited when it is nil
+	RateLimitRepository model.RateLimitRepository
+	// IPRateLimit applies to each client IP per route
+	IPRateLimit model.RateLimit
+	// EmailRateLimit applies to each email signing in or asking for a
+	// password reset, and to each user changing their password
+	EmailRateLimit model.RateLimit
 }
 
 func NewHandler(c *Config) {
 	h := &Handler{
-		UserService:  c.UserService,
-		TokenService: c.TokenService,
+		UserService:         c.UserService,
+		TokenService:        c.TokenService,
+		VerificationService: c.VerificationService,
+		PasswordService:     c.PasswordService,
+		TwoFactorService:    c.TwoFactorService,
+		OIDCService:         c.OIDCService,
+		MaxBodyBytes:        c.MaxBodyBytes,
 	}
 
-	g := c.R.Group(os.Getenv("AUTH_API_URL"))
-	g.GET("/me", h.Me)
-	g.POST("/sign-up", h.SignUp)
-	g.POST("/sign-in", h.SignIn)
-	g.POST("/sign-out", h.SignOut)
-	g.POST("/token", h.Token)
-	g.POST("/image", h.Image)
-	g.DELETE("/image", h.DeleteImage)
-	g.PUT("/details", h.Details)
-}
+	g := c.R.Group(c.BaseURL)
 
-func (h *Handler) SignIn(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "It's sign in",
-	})
-}
+	g.GET("/me", middleware.AuthUser(h.TokenService), h.Me)
+	g.POST("/sign-out", middleware.AuthUser(h.TokenService), h.SignOut)
+	g.POST("/image", middleware.AuthUser(h.TokenService), h.Image)
+	g.DELETE("/image", middleware.AuthUser(h.TokenService), h.DeleteImage)
+	g.PUT("/details", middleware.AuthUser(h.TokenService), h.Details)
+	g.POST("/2fa/enroll", middleware.AuthUser(h.TokenService), h.EnrollTwoFactor)
+	g.POST("/2fa/confirm", middleware.AuthUser(h.TokenService), h.ConfirmTwoFactor)
+	g.POST("/2fa/disable", middleware.AuthUser(h.TokenService), h.DisableTwoFactor)
 
-func (h *Handler) SignOut(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "It's sign out",
-	})
-}
+	if c.RateLimitRepository != nil {
+		limitIP := func(route string) gin.HandlerFunc {
+			return middleware.RateLimit(c.RateLimitRepository, c.IPRateLimit, middleware.ByIP(route))
+		}
 
-func (h *Handler) Token(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "It's token",
-	})
-}
+		if c.OIDCService != nil {
+			g.GET("/oidc/:provider/login", limitIP("oidc-login"), h.OIDCLogin)
+			g.GET("/oidc/:provider/callback", limitIP("oidc-callback"), h.OIDCCallback)
+		}
 
-func (h *Handler) Image(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "It's image",
-	})
-}
+		g.POST("/sign-up", limitIP("sign-up"), h.SignUp)
+		g.POST("/sign-in", limitIP("sign-in"), middleware.RateLimit(c.RateLimitRepository, c.EmailRateLimit, middleware.ByEmail("sign-in")), h.SignIn)
+		g.POST("/sign-in/2fa", limitIP("sign-in-2fa"), h.SignInTwoFactor)
+		g.POST("/token", limitIP("token"), h.Token)
+		g.POST("/password/forgot", limitIP("password-forgot"), middleware.RateLimit(c.RateLimitRepository, c.EmailRateLimit, middleware.ByEmail("password-forgot")), h.ForgotPassword)
+		g.POST("/password/reset", limitIP("password-reset"), h.ResetPassword)
+		g.POST("/verify-email/resend", middleware.AuthUser(h.TokenService), limitIP("verify-email-resend"), h.ResendVerification)
+		g.PUT("/password", middleware.AuthUser(h.TokenService), limitIP("password-change"), middleware.RateLimit(c.RateLimitRepository, c.EmailRateLimit, middleware.ByUser("password-change")), h.ChangePassword)
+	} else {
+		g.POST("/sign-up", h.SignUp)
+		g.POST("/sign-in", h.SignIn)
+		g.POST("/sign-in/2fa", h.SignInTwoFactor)
+		g.POST("/token", h.Token)
+		g.POST("/password/forgot", h.ForgotPassword)
+		g.POST("/password/reset", h.ResetPassword)
+		g.POST("/verify-email/resend", middleware.AuthUser(h.TokenService), h.ResendVerification)
+		g.PUT("/password", middleware.AuthUser(h.TokenService), h.ChangePassword)
 
-func (h *Handler) DeleteImage(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "It's delete image",
-	})
-}
+		if c.OIDCService != nil {
+			g.GET("/oidc/:provider/login", h.OIDCLogin)
+			g.GET("/oidc/:provider/callback", h.OIDCCallback)
+		}
+	}
 
-func (h *Handler) Details(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "It's details",
-	})
+	g.GET("/verify-email", h.VerifyEmail)
+	g.POST("/verify-email", h.VerifyEmail)
 }
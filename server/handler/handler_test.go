@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestAuthedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockTokenService.On("ValidateIDToken", "invalid").Return(nil, apperrors.NewAuthorization("Unable to verify user from idToken"))
+
+	router := gin.Default()
+
+	// none of the services are reached without a valid ID token
+	NewHandler(&Config{
+		R:                   router,
+		UserService:         new(mocks.MockUserService),
+		TokenService:        mockTokenService,
+		VerificationService: new(mocks.MockVerificationService),
+		TwoFactorService:    new(mocks.MockTwoFactorService),
+	})
+
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/me"},
+		{http.MethodPost, "/sign-out"},
+		{http.MethodPost, "/image"},
+		{http.MethodDelete, "/image"},
+		{http.MethodPut, "/details"},
+		{http.MethodPut, "/password"},
+		{http.MethodPost, "/2fa/enroll"},
+		{http.MethodPost, "/2fa/confirm"},
+		{http.MethodPost, "/2fa/disable"},
+		{http.MethodPost, "/verify-email/resend"},
+	}
+
+	for _, route := range routes {
+		for _, authorization := range []string{"", "Bearer ", "Bearer invalid"} {
+			rr := newRecorder(t)
+
+			request, err := http.NewRequest(route.method, route.path, nil)
+			assert.NoError(t, err)
+
+			if authorization != "" {
+				request.Header.Set("Authorization", authorization)
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, "%s %s with %q", route.method, route.path, authorization)
+		}
+	}
+
+	mockTokenService.AssertNotCalled(t, "Signout", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+// testIDToken is the ID token clients of authedRouter send
+const testIDToken = "testIDToken"
+
+// authedRouter returns a router whose requests carry testIDToken as
+// their Bearer token. Handlers still run behind the AuthUser
+// middleware, which gets u from validating it with ts
+func authedRouter(ts *mocks.MockTokenService, u *model.User) *gin.Engine {
+	ts.On("ValidateIDToken", testIDToken).Return(u, nil)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Request.Header.Set("Authorization", "Bearer "+testIDToken)
+	})
+
+	return router
+}
+
+// newRecorder returns a response recorder whose body is checked
+// for password fields once the test using it has finished. Every
+// handler test records responses through it
+func newRecorder(t *testing.T) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+
+	t.Cleanup(func() {
+		assertNoPasswordKey(t, rr.Body.Bytes())
+	})
+
+	return rr
+}
+
+// assertNoPasswordKey fails the test if a JSON body has an object key
+// mentioning a password at any depth. Non-JSON bodies are ignored
+func assertNoPasswordKey(t *testing.T, body []byte) {
+	t.Helper()
+
+	var decoded interface{}
+
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return
+	}
+
+	if path, found := findPasswordKey(decoded, "$"); found {
+		t.Errorf("response contains a password field at %v: %s", path, body)
+	}
+}
+
+// findPasswordKey walks a decoded JSON value and returns the path
+// of the first key containing "password"
+func findPasswordKey(v interface{}, path string) (string, bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			childPath := path + "." + key
+
+			if strings.Contains(strings.ToLower(key), "password") {
+				return childPath, true
+			}
+
+			if p, found := findPasswordKey(child, childPath); found {
+				return p, true
+			}
+		}
+	case []interface{}:
+		for _, child := range val {
+			if p, found := findPasswordKey(child, path+"[]"); found {
+				return p, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func TestAssertNoPasswordKey(t *testing.T) {
+	cases := map[string]bool{
+		`{"user":{"uid":"1","email":"a@b.c"}}`:        false,
+		`{"error":{"type":"BAD_REQUEST"}}`:            false,
+		`not json`:                                    false,
+		`{"user":{"password":"hash"}}`:                true,
+		`{"users":[{"uid":"1"},{"Password":"hash"}]}`: true,
+		`{"invalidArgs":[{"field":"Password"}]}`:      false,
+		`{"data":{"nested":{"currentPassword":"x"}}}`: true,
+	}
+
+	for body, wantFound := range cases {
+		var decoded interface{}
+		_ = json.Unmarshal([]byte(body), &decoded)
+
+		_, found := findPasswordKey(decoded, "$")
+
+		if found != wantFound {
+			t.Errorf("findPasswordKey(%v) = %v, want %v", body, found, wantFound)
+		}
+	}
+}
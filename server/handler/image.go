@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// validImageTypes are the image types accepted as profile images
+var validImageTypes = []string{
+	"image/jpeg",
+	"image/png",
+}
+
+// Image handler stores the "imageFile" of a multipart/form-data
+// request as the signed in user's profile image
+func (h *Handler) Image(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	uid := user.(*model.User).UID
+
+	if c.Request.ContentLength > h.MaxBodyBytes {
+		err := apperrors.NewPayloadTooLarge(h.MaxBodyBytes, c.Request.ContentLength)
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// limits the body when Content-Length is unknown or lies
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxBodyBytes)
+
+	imageFileHeader, err := c.FormFile("imageFile")
+
+	// check for error before checking for non-nil header
+	if err != nil {
+		logging.FromContext(c).Info("Unable to parse multipart/form-data", "err", err)
+
+		var maxBytesErr *http.MaxBytesError
+
+		if errors.As(err, &maxBytesErr) {
+			err := apperrors.NewPayloadTooLarge(h.MaxBodyBytes, c.Request.ContentLength)
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		if errors.Is(err, http.ErrMissingFile) {
+			err := apperrors.NewBadRequest("Must include an imageFile")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		e := apperrors.NewBadRequest("Unable to parse multipart/form-data")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	imageFile, err := imageFileHeader.Open()
+
+	if err != nil {
+		logging.FromContext(c).Error("Unable to open imageFile", "err", err)
+		e := apperrors.NewInternal()
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	// don't trust the client supplied Content-Type, sniff the contents
+	mimeType, err := mimetype.DetectReader(imageFile)
+	imageFile.Close()
+
+	if err != nil || !mimetype.EqualsAny(mimeType.String(), validImageTypes...) {
+		logging.FromContext(c).Info("Image is not an allowable mime-type", "mime_type", mimeType)
+		e := apperrors.NewBadRequest("imageFile must be 'image/jpeg' or 'image/png'")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	imageFileHeader.Header.Set("Content-Type", mimeType.String())
+
+	updatedUser, err := h.UserService.SetProfileImage(c, uid, imageFileHeader)
+
+	if err != nil {
+		logging.FromContext(c).Error("Failed to set profile image for user", "uid", uid, "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": updatedUser.Public(),
+	})
+}
+
+// DeleteImage handler removes the signed in user's profile image
+func (h *Handler) DeleteImage(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	uid := user.(*model.User).UID
+
+	updatedUser, err := h.UserService.ClearProfileImage(c, uid)
+
+	if err != nil {
+		logging.FromContext(c).Error("Failed to delete profile image for user", "uid", uid, "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": updatedUser.Public(),
+	})
+}
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+// newMultipartImage builds a multipart/form-data body with contents
+// in the given form field
+func newMultipartImage(t *testing.T, fieldName string, contents []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile(fieldName, "image.png")
+	require.NoError(t, err)
+
+	_, err = io.Copy(part, bytes.NewReader(contents))
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Close())
+
+	return body, writer.FormDataContentType()
+}
+
+// pngBytes encodes a tiny png image
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+
+	return buf.Bytes()
+}
+
+func TestImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MaxBodyBytes: 4 * 1024 * 1024,
+		})
+
+		mockUserResp := &model.User{
+			UID:      uid,
+			Email:    "vuluu040320@gmail.com",
+			ImageUrl: "/images/avatar.png",
+		}
+
+		mockUserService.
+			On("SetProfileImage", mock.AnythingOfType("*gin.Context"), uid, mock.AnythingOfType("*multipart.FileHeader")).
+			Run(func(args mock.Arguments) {
+				header := args.Get(2).(*multipart.FileHeader)
+				assert.Equal(t, "image/png", header.Header.Get("Content-Type"))
+			}).
+			Return(mockUserResp, nil)
+
+		body, contentType := newMultipartImage(t, "imageFile", pngBytes(t))
+
+		rr := newRecorder(t)
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": mockUserResp.Public(),
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Missing imageFile", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MaxBodyBytes: 4 * 1024 * 1024,
+		})
+
+		body, contentType := newMultipartImage(t, "notImageFile", pngBytes(t))
+
+		rr := newRecorder(t)
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "SetProfileImage")
+	})
+
+	t.Run("Disallowed mime-type", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MaxBodyBytes: 4 * 1024 * 1024,
+		})
+
+		// a png Content-Type header does not make a text file an image
+		body, contentType := newMultipartImage(t, "imageFile", []byte("just some text"))
+
+		rr := newRecorder(t)
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "SetProfileImage")
+	})
+
+	t.Run("Payload too large", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MaxBodyBytes: 16,
+		})
+
+		body, contentType := newMultipartImage(t, "imageFile", pngBytes(t))
+		contentLength := int64(body.Len())
+
+		rr := newRecorder(t)
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": apperrors.NewPayloadTooLarge(16, contentLength),
+		})
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertNotCalled(t, "SetProfileImage")
+	})
+
+	t.Run("Payload too large without Content-Length", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MaxBodyBytes: 16,
+		})
+
+		body, contentType := newMultipartImage(t, "imageFile", pngBytes(t))
+
+		rr := newRecorder(t)
+		// hide the length of the body from the handler
+		request, _ := http.NewRequest(http.MethodPost, "/image", io.NopCloser(body))
+		request.Header.Set("Content-Type", contentType)
+		request.ContentLength = -1
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		mockUserService.AssertNotCalled(t, "SetProfileImage")
+	})
+
+	t.Run("Error from UserService", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MaxBodyBytes: 4 * 1024 * 1024,
+		})
+
+		mockError := apperrors.NewInternal()
+
+		mockUserService.
+			On("SetProfileImage", mock.AnythingOfType("*gin.Context"), uid, mock.AnythingOfType("*multipart.FileHeader")).
+			Return(nil, mockError)
+
+		body, contentType := newMultipartImage(t, "imageFile", pngBytes(t))
+
+		rr := newRecorder(t)
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+}
+
+func TestDeleteImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserResp := &model.User{
+			UID:   uid,
+			Email: "vuluu040320@gmail.com",
+		}
+
+		mockUserService.On("ClearProfileImage", mock.AnythingOfType("*gin.Context"), uid).Return(mockUserResp, nil)
+
+		rr := newRecorder(t)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": mockUserResp.Public(),
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("ClearProfileImage Error", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockError := apperrors.NewInternal()
+
+		mockUserService.On("ClearProfileImage", mock.AnythingOfType("*gin.Context"), uid).Return(nil, mockError)
+
+		rr := newRecorder(t)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+}
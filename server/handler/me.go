@@ -1,10 +1,10 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
 	"github.com/vuluu2k/remember_fullstack/server/model"
 	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
 )
@@ -13,7 +13,7 @@ func (h *Handler) Me(c *gin.Context) {
 	user, exists := c.Get("user")
 
 	if !exists {
-		log.Printf("Unable to extract user from request context for unknown reason: %v\n", c)
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
 		err := apperrors.NewInternal()
 		c.JSON(err.Status(), gin.H{
 			"error": err,
@@ -26,7 +26,7 @@ func (h *Handler) Me(c *gin.Context) {
 	u, err := h.UserService.Get(c, uid)
 
 	if err != nil {
-		log.Printf("Unable to find user: %v\n%v", uid, err)
+		logging.FromContext(c).Error("Unable to find user", "uid", uid, "err", err)
 		e := apperrors.NewNotFound("user", uid.String())
 
 		c.JSON(e.Status(), gin.H{
@@ -36,6 +36,6 @@ func (h *Handler) Me(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"user": u,
+		"user": u.Public(),
 	})
 }
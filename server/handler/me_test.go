@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -22,28 +21,28 @@ func TestMe(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		uid, _ := uuid.NewRandom()
 
+		// the stored password hash must never reach the response
 		mockUserResp := &model.User{
-			UID:   uid,
-			Email: "vuluu040320@gmail.com",
-			Name:  "Vũ Lưu",
+			UID:      uid,
+			Email:    "vuluu040320@gmail.com",
+			Password: "hashedpassword",
+			Name:     "Vũ Lưu",
 		}
 
 		mockUserService := new(mocks.MockUserService)
 
 		mockUserService.On("Get", mock.AnythingOfType("*gin.Context"), uid).Return(mockUserResp, nil)
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
-		router := gin.Default()
-
-		router.Use(func(c *gin.Context) {
-			c.Set("user", &model.User{
-				UID: uid,
-			})
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, &model.User{
+			UID: uid,
 		})
 
 		NewHandler(&Config{
-			R:           router,
-			UserService: mockUserService,
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
 		})
 
 		request, err := http.NewRequest("GET", "/me", nil)
@@ -53,7 +52,7 @@ func TestMe(t *testing.T) {
 		assert.NoError(t, err)
 
 		respBody, err := json.Marshal(gin.H{
-			"user": mockUserResp,
+			"user": mockUserResp.Public(),
 		})
 
 		assert.NoError(t, err)
@@ -64,18 +63,20 @@ func TestMe(t *testing.T) {
 		mockUserService.AssertExpectations(t)
 	})
 
-	t.Run("NoContextUser", func(t *testing.T) {
+	t.Run("No Authorization header", func(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 		mockUserService.On("Get", mock.Anything, mock.Anything).Return(nil, nil)
+		mockTokenService := new(mocks.MockTokenService)
 
 		// a response recorder for getting written http response
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
-		// do not append user to context
+		// requests carry no ID token
 		router := gin.Default()
 		NewHandler(&Config{
-			R:           router,
-			UserService: mockUserService,
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
 		})
 
 		request, err := http.NewRequest(http.MethodGet, "/me", nil)
@@ -83,8 +84,9 @@ func TestMe(t *testing.T) {
 
 		router.ServeHTTP(rr, request)
 
-		assert.Equal(t, http.StatusInternalServerError, rr.Code)
-		mockUserService.AssertNotCalled(t, "Get", mock.Anything)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockUserService.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+		mockTokenService.AssertNotCalled(t, "ValidateIDToken", mock.Anything)
 	})
 
 	t.Run("NotFound", func(t *testing.T) {
@@ -93,19 +95,17 @@ func TestMe(t *testing.T) {
 		mockUserService.On("Get", mock.Anything, uid).Return(nil, fmt.Errorf("Some error down call chain"))
 
 		// a response recorder for getting written http response
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
-		router := gin.Default()
-		router.Use(func(c *gin.Context) {
-			c.Set("user", &model.User{
-				UID: uid,
-			},
-			)
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, &model.User{
+			UID: uid,
 		})
 
 		NewHandler(&Config{
-			R:           router,
-			UserService: mockUserService,
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
 		})
 
 		request, err := http.NewRequest(http.MethodGet, "/me", nil)
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// AuthUser extracts a user from the Authorization header
+// which is of the form "Bearer token"
+// It sets the user to the context if the user exists
+func AuthUser(s model.TokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idToken, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+		if !ok || idToken == "" {
+			err := apperrors.NewAuthorization("Must provide Authorization header with format `Bearer {token}`")
+
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		// validate ID token here
+		user, err := s.ValidateIDToken(idToken)
+
+		if err != nil {
+			err := apperrors.NewAuthorization("Provided token is invalid")
+
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "uid", user.UID))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestAuthUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "vuluu040320@gmail.com",
+	}
+
+	// Since we mock tokenService, we need not
+	// create actual JWTs
+	validTokenHeader := "validTokenString"
+	invalidTokenHeader := "invalidTokenString"
+	invalidTokenErr := apperrors.NewAuthorization("Unable to verify user from idToken")
+
+	mockTokenService.On("ValidateIDToken", validTokenHeader).Return(u, nil)
+	mockTokenService.On("ValidateIDToken", invalidTokenHeader).Return(nil, invalidTokenErr)
+
+	t.Run("Adds a user to context", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		// creates a test context and gin engine
+		_, r := gin.CreateTestContext(rr)
+
+		// will be populated with user in a handler
+		// if AuthUser middleware is successful
+		var contextUser *model.User
+
+		// a trailing handler is the only way to observe the context
+		// as modified by the middleware
+		r.GET("/me", AuthUser(mockTokenService), func(c *gin.Context) {
+			contextKeyVal, _ := c.Get("user")
+			contextUser = contextKeyVal.(*model.User)
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", validTokenHeader))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, u, contextUser)
+
+		mockTokenService.AssertCalled(t, "ValidateIDToken", validTokenHeader)
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		// creates a test context and gin engine
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/me", AuthUser(mockTokenService))
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", invalidTokenHeader))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertCalled(t, "ValidateIDToken", invalidTokenHeader)
+	})
+
+	t.Run("Missing Authorization Header", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		// creates a test context and gin engine
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/me", AuthUser(mockTokenService))
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNumberOfCalls(t, "ValidateIDToken", 2)
+	})
+
+	t.Run("Malformed Authorization Header", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		// creates a test context and gin engine
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/me", AuthUser(mockTokenService))
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Token %s", validTokenHeader))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNumberOfCalls(t, "ValidateIDToken", 2)
+	})
+
+	t.Run("Does not call next handler on failure", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+
+		nextCalled := false
+
+		r.GET("/me", AuthUser(mockTokenService), func(c *gin.Context) {
+			nextCalled = true
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", invalidTokenHeader))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.False(t, nextCalled)
+		mockTokenService.AssertExpectations(t)
+	})
+}
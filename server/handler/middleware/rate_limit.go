@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// maxEmailBodyBytes is the largest body ByEmail reads looking for the
+// email. Larger ones are refused, the email could hide past any prefix
+const maxEmailBodyBytes = 1 << 16
+
+// RateKey names the token bucket a request takes from. Requests for
+// which it returns "" are not limited, unless it aborted them
+type RateKey func(c *gin.Context) string
+
+// ByIP gives every client IP a bucket per name
+func ByIP(name string) RateKey {
+	return func(c *gin.Context) string {
+		return name + ":ip:" + c.ClientIP()
+	}
+}
+
+// ByUser gives every signed in user a bucket per name. It must run
+// after AuthUser, requests without a user are not limited
+func ByUser(name string) RateKey {
+	return func(c *gin.Context) string {
+		user, exists := c.Get("user")
+
+		if !exists {
+			return ""
+		}
+
+		return name + ":user:" + user.(*model.User).UID.String()
+	}
+}
+
+// ByEmail gives every submitted email a bucket per name, so one
+// account can't be targeted from many IPs. The email is read with the
+// binding ShouldBind picks for the request, whatever its content type,
+// and the body is left for the handler to bind. Bodies over
+// maxEmailBodyBytes are rejected
+func ByEmail(name string) RateKey {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxEmailBodyBytes))
+
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+
+			if errors.As(err, &tooLarge) {
+				e := apperrors.NewPayloadTooLarge(maxEmailBodyBytes, c.Request.ContentLength)
+				c.AbortWithStatusJSON(e.Status(), gin.H{
+					"error": e,
+				})
+				return ""
+			}
+
+			logging.FromContext(c).Info("Unable to read request body", "err", err)
+			e := apperrors.NewBadRequest("Unable to read request body")
+			c.AbortWithStatusJSON(e.Status(), gin.H{
+				"error": e,
+			})
+			return ""
+		}
+
+		c.Request.Body = readCloser{bytes.NewReader(body), c.Request.Body}
+
+		peek := c.Request.Clone(c)
+		peek.Body = io.NopCloser(bytes.NewReader(body))
+
+		defer func() {
+			if peek.MultipartForm != nil {
+				peek.MultipartForm.RemoveAll()
+			}
+		}()
+
+		var req struct {
+			Email string `json:"email" form:"email" xml:"email" yaml:"email" toml:"email"`
+		}
+
+		// the handler binds the same body and fails the same way
+		if err := binding.Default(c.Request.Method, c.ContentType()).Bind(peek, &req); err != nil {
+			return ""
+		}
+
+		email := strings.ToLower(strings.TrimSpace(req.Email))
+
+		if email == "" {
+			return ""
+		}
+
+		return name + ":email:" + email
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// RateLimit takes a token from the bucket of each request's key and
+// rejects the request with a Retry-After header once it is empty.
+// Requests are let through if the store fails, so an outage of it
+// does not lock everyone out
+func RateLimit(r model.RateLimitRepository, limit model.RateLimit, key RateKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+
+		if c.IsAborted() {
+			return
+		}
+
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		wait, err := r.Take(c, k, limit)
+
+		if err != nil {
+			logging.FromContext(c).Error("Unable to check rate limit, letting request through", "err", err)
+			c.Next()
+			return
+		}
+
+		if wait > 0 {
+			retryAfter := int64(math.Ceil(wait.Seconds()))
+			err := apperrors.NewTooManyRequests(retryAfter)
+
+			logging.FromContext(c).Warn("Rate limit exceeded", "key", k)
+
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limit := model.RateLimit{Burst: 5, Interval: time.Minute}
+
+	t.Run("Allows requests with tokens left", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.On("Take", mock.Anything, "sign-up:ip:192.0.2.1", limit).Return(time.Duration(0), nil)
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		r.POST("/sign-up", RateLimit(mockRateLimitRepository, limit, ByIP("sign-up")), func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-up", http.NoBody)
+		request.RemoteAddr = "192.0.2.1:1234"
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockRateLimitRepository.AssertExpectations(t)
+	})
+
+	t.Run("Rejects requests once empty", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.On("Take", mock.Anything, "sign-up:ip:192.0.2.1", limit).Return(1500*time.Millisecond, nil)
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		handlerCalled := false
+
+		r.POST("/sign-up", RateLimit(mockRateLimitRepository, limit, ByIP("sign-up")), func(c *gin.Context) {
+			handlerCalled = true
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-up", http.NoBody)
+		request.RemoteAddr = "192.0.2.1:1234"
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":{"type":"TOO_MANY_REQUESTS","message":"Too many requests. Try again in 2 seconds"}}`, rr.Body.String())
+		assert.False(t, handlerCalled)
+	})
+
+	t.Run("Lets requests through when the store fails", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.On("Take", mock.Anything, mock.Anything, limit).Return(time.Duration(0), errors.New("redis down"))
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		r.POST("/token", RateLimit(mockRateLimitRepository, limit, ByIP("token")), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/token", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Limits by submitted email and keeps the body", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.On("Take", mock.Anything, "sign-in:email:bob@bob.com", limit).Return(time.Duration(0), nil)
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		body := []byte(`{"email":" Bob@Bob.com ","password":"avalidpassword"}`)
+		var handlerBody []byte
+
+		r.POST("/sign-in", RateLimit(mockRateLimitRepository, limit, ByEmail("sign-in")), func(c *gin.Context) {
+			handlerBody, _ = io.ReadAll(c.Request.Body)
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBuffer(body))
+		request.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, body, handlerBody)
+		mockRateLimitRepository.AssertExpectations(t)
+	})
+
+	t.Run("Limits by email of form bodies", func(t *testing.T) {
+		var multipartBody bytes.Buffer
+		w := multipart.NewWriter(&multipartBody)
+		w.WriteField("email", "Bob@Bob.com")
+		w.WriteField("password", "avalidpassword")
+		w.Close()
+
+		bodies := map[string][]byte{
+			"application/x-www-form-urlencoded": []byte("email=+Bob%40Bob.com+&password=avalidpassword"),
+			w.FormDataContentType():             multipartBody.Bytes(),
+		}
+
+		for contentType, body := range bodies {
+			mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+			mockRateLimitRepository.On("Take", mock.Anything, "sign-in:email:bob@bob.com", limit).Return(time.Minute, nil)
+
+			rr := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(rr)
+
+			handlerCalled := false
+
+			r.POST("/sign-in", RateLimit(mockRateLimitRepository, limit, ByEmail("sign-in")), func(c *gin.Context) {
+				handlerCalled = true
+			})
+
+			request, _ := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewReader(body))
+			request.Header.Set("Content-Type", contentType)
+			r.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusTooManyRequests, rr.Code, contentType)
+			assert.False(t, handlerCalled, contentType)
+			mockRateLimitRepository.AssertExpectations(t)
+		}
+	})
+
+	t.Run("Keeps form bodies for the handler", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.On("Take", mock.Anything, "sign-in:email:bob@bob.com", limit).Return(time.Duration(0), nil)
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		var req struct {
+			Email    string `form:"email"`
+			Password string `form:"password"`
+		}
+
+		r.POST("/sign-in", RateLimit(mockRateLimitRepository, limit, ByEmail("sign-in")), func(c *gin.Context) {
+			assert.NoError(t, c.ShouldBind(&req))
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBufferString("email=bob%40bob.com&password=avalidpassword"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "bob@bob.com", req.Email)
+		assert.Equal(t, "avalidpassword", req.Password)
+		mockRateLimitRepository.AssertExpectations(t)
+	})
+
+	t.Run("Rejects bodies too large to read the email from", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		handlerCalled := false
+
+		r.POST("/sign-in", RateLimit(mockRateLimitRepository, limit, ByEmail("sign-in")), func(c *gin.Context) {
+			handlerCalled = true
+		})
+
+		// valid JSON whose email only comes after the padding
+		body := `{"pad":"` + strings.Repeat(" ", maxEmailBodyBytes) + `","email":"bob@bob.com","password":"avalidpassword"}`
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBufferString(body))
+		request.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.False(t, handlerCalled)
+		mockRateLimitRepository.AssertNotCalled(t, "Take", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Does not limit by email without one", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		r.POST("/sign-in", RateLimit(mockRateLimitRepository, limit, ByEmail("sign-in")), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBufferString(`{"password":"avalidpassword"}`))
+		request.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRateLimitRepository.AssertNotCalled(t, "Take", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Limits by signed in user", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.On("Take", mock.Anything, "password-change:user:"+uid.String(), limit).Return(time.Duration(0), nil)
+
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		setUser := func(c *gin.Context) {
+			c.Set("user", &model.User{UID: uid})
+		}
+
+		r.PUT("/password", setUser, RateLimit(mockRateLimitRepository, limit, ByUser("password-change")), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/password", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRateLimitRepository.AssertExpectations(t)
+	})
+}
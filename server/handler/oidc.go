@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// oidcStateCookie ties a sign in with an identity provider to the
+// browser that started it
+const oidcStateCookie = "oidc_state"
+
+// oidcCallbackReq is the query identity providers send users back with
+type oidcCallbackReq struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+// OIDCLogin handler sends the user to sign in with the identity
+// provider named in the route
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	authURL, state, err := h.OIDCService.AuthURL(c, c.Param("provider"))
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to start OIDC sign in", "provider", c.Param("provider"), "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	// the callback route shares the directory of this one
+	setOIDCStateCookie(c, state, 0)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback handler completes signing in with the identity provider
+// named in the route, responding like SignIn
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	// each state is only good for one callback
+	state, cookieErr := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		logging.FromContext(c).Info("Identity provider refused sign in", "provider", c.Param("provider"), "error", providerErr)
+
+		err := apperrors.NewAuthorization("Sign in with the identity provider was cancelled or refused")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req oidcCallbackReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	// without it, someone could sign the browser into their own account
+	// by sending a link to their callback
+	if cookieErr != nil || state != req.State {
+		logging.FromContext(c).Info("OIDC state does not match the browser's", "provider", c.Param("provider"))
+
+		err := apperrors.NewAuthorization("Invalid sign in state")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	u, err := h.OIDCService.Callback(c, c.Param("provider"), req.State, req.Code)
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to sign in with OIDC", "provider", c.Param("provider"), "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	h.signedIn(c, u)
+}
+
+// setOIDCStateCookie sets, or with a negative maxAge clears, the state
+// cookie for the routes of the current provider
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path.Dir(c.Request.URL.Path),
+		MaxAge:   maxAge,
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestOIDC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:           uid,
+		Email:         "vuluu040320@gmail.com",
+		EmailVerified: true,
+	}
+
+	authURL := "https://idp.test/authorize?state=thestate"
+
+	// callback returns the provider's redirect back to us, carrying
+	// the state cookie when cookieState isn't empty
+	callback := func(t *testing.T, router *gin.Engine, query string, cookieState string) (int, []byte, *http.Cookie) {
+		t.Helper()
+
+		rr := newRecorder(t)
+
+		request, err := http.NewRequest(http.MethodGet, "/oidc/fake/callback?"+query, nil)
+		require.NoError(t, err)
+
+		if cookieState != "" {
+			request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookieState})
+		}
+
+		router.ServeHTTP(rr, request)
+
+		var cleared *http.Cookie
+
+		for _, cookie := range rr.Result().Cookies() {
+			if cookie.Name == oidcStateCookie {
+				cleared = cookie
+			}
+		}
+
+		return rr.Code, rr.Body.Bytes(), cleared
+	}
+
+	t.Run("Login redirects to the provider", func(t *testing.T) {
+		mockOIDCService := new(mocks.MockOIDCService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+			OIDCService:      mockOIDCService,
+		})
+
+		mockOIDCService.On("AuthURL", mock.AnythingOfType("*gin.Context"), "fake").Return(authURL, "thestate", nil)
+
+		rr := newRecorder(t)
+
+		request, err := http.NewRequest(http.MethodGet, "/oidc/fake/login", nil)
+		require.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusFound, rr.Code)
+		assert.Equal(t, authURL, rr.Header().Get("Location"))
+
+		cookies := rr.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, oidcStateCookie, cookies[0].Name)
+		assert.Equal(t, "thestate", cookies[0].Value)
+		assert.Equal(t, "/oidc/fake", cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	})
+
+	t.Run("Login with an unknown provider", func(t *testing.T) {
+		mockOIDCService := new(mocks.MockOIDCService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+			OIDCService:      mockOIDCService,
+		})
+
+		mockOIDCService.On("AuthURL", mock.AnythingOfType("*gin.Context"), "missing").Return("", "", apperrors.NewNotFound("provider", "missing"))
+
+		rr := newRecorder(t)
+
+		request, err := http.NewRequest(http.MethodGet, "/oidc/missing/login", nil)
+		require.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, rr.Result().Cookies())
+	})
+
+	t.Run("Callback responds with tokens", func(t *testing.T) {
+		mockOIDCService := new(mocks.MockOIDCService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+			OIDCService:      mockOIDCService,
+		})
+
+		tokens := &model.TokenPair{
+			TokenID:      "idToken",
+			RefreshToken: "refreshToken",
+		}
+
+		mockOIDCService.On("Callback", mock.AnythingOfType("*gin.Context"), "fake", "thestate", "thecode").Return(u, nil)
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*gin.Context"), u, "").Return(tokens, nil)
+
+		status, body, cleared := callback(t, router, "code=thecode&state=thestate", "thestate")
+
+		assert.Equal(t, http.StatusOK, status)
+
+		expected, err := json.Marshal(gin.H{"tokens": tokens})
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(body))
+
+		require.NotNil(t, cleared)
+		assert.Equal(t, -1, cleared.MaxAge)
+	})
+
+	t.Run("Callback of a two-factor user", func(t *testing.T) {
+		mockOIDCService := new(mocks.MockOIDCService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+			OIDCService:      mockOIDCService,
+		})
+
+		totpUser := &model.User{UID: uid, Email: u.Email, TOTPEnabled: true}
+
+		mockOIDCService.On("Callback", mock.AnythingOfType("*gin.Context"), "fake", "thestate", "thecode").Return(totpUser, nil)
+		mockTwoFactorService.On("NewChallenge", mock.AnythingOfType("*gin.Context"), totpUser).Return("challenge", nil)
+
+		status, body, _ := callback(t, router, "code=thecode&state=thestate", "thestate")
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"challengeToken":"challenge"}`, string(body))
+
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Callback without the browser's state", func(t *testing.T) {
+		mockOIDCService := new(mocks.MockOIDCService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+			OIDCService:      mockOIDCService,
+		})
+
+		status, _, _ := callback(t, router, "code=thecode&state=thestate", "")
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status, _, _ = callback(t, router, "code=thecode&state=thestate", "otherstate")
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		mockOIDCService.AssertNotCalled(t, "Callback", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Callback fails", func(t *testing.T) {
+		mockOIDCService := new(mocks.MockOIDCService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+			OIDCService:      mockOIDCService,
+		})
+
+		mockOIDCService.On("Callback", mock.AnythingOfType("*gin.Context"), "fake", "thestate", "thecode").Return(nil, apperrors.NewConflict("email", u.Email))
+
+		status, _, _ := callback(t, router, "code=thecode&state=thestate", "thestate")
+
+		assert.Equal(t, http.StatusConflict, status)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Provider refused", func(t *testing.T) {
+		mockOIDCService := new(mocks.MockOIDCService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+			OIDCService:      mockOIDCService,
+		})
+
+		status, _, _ := callback(t, router, "error=access_denied&state=thestate", "thestate")
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status, _, _ = callback(t, router, "state=thestate", "thestate")
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		mockOIDCService.AssertNotCalled(t, "Callback", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Disabled without OIDCService", func(t *testing.T) {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R: router,
+		})
+
+		rr := newRecorder(t)
+
+		request, err := http.NewRequest(http.MethodGet, "/oidc/fake/login", nil)
+		require.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+type forgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// resetPasswordReq takes the same passwords as signUpReq
+type resetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,gte=6,lte=30"`
+}
+
+// ForgotPassword handler emails a password reset link. It responds the
+// same whether or not the email belongs to an account, so accounts
+// cannot be enumerated
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	if err := h.PasswordService.ForgotPassword(c, req.Email); err != nil {
+		logging.FromContext(c).Error("Failed to send password reset email", "err", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If an account uses this email, a link to reset its password has been sent",
+	})
+}
+
+// ResetPassword handler sets a new password with the token of a
+// reset link, signing the user out everywhere
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req resetPasswordReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	if err := h.PasswordService.ResetPassword(c, req.Token, req.Password); err != nil {
+		logging.FromContext(c).Info("Failed to reset password", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your password has been reset. Sign in with your new password",
+	})
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestForgotPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Invalid email", func(t *testing.T) {
+		mockPasswordService := new(mocks.MockPasswordService)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:               router,
+			PasswordService: mockPasswordService,
+		})
+
+		reqBody, err := json.Marshal(gin.H{
+			"email": "notanemail",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockPasswordService.AssertNotCalled(t, "ForgotPassword")
+	})
+
+	t.Run("Same response whether or not the email is sent", func(t *testing.T) {
+		var bodies [][]byte
+
+		for _, serviceErr := range []error{nil, apperrors.NewInternal()} {
+			mockPasswordService := new(mocks.MockPasswordService)
+			mockPasswordService.On("ForgotPassword", mock.AnythingOfType("*gin.Context"), "vuluu040320@gmail.com").Return(serviceErr)
+
+			rr := newRecorder(t)
+			router := gin.Default()
+
+			NewHandler(&Config{
+				R:               router,
+				PasswordService: mockPasswordService,
+			})
+
+			reqBody, err := json.Marshal(gin.H{
+				"email": "vuluu040320@gmail.com",
+			})
+			assert.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(reqBody))
+			assert.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			mockPasswordService.AssertExpectations(t)
+
+			bodies = append(bodies, rr.Body.Bytes())
+		}
+
+		assert.Equal(t, bodies[0], bodies[1])
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Password too short", func(t *testing.T) {
+		mockPasswordService := new(mocks.MockPasswordService)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:               router,
+			PasswordService: mockPasswordService,
+		})
+
+		reqBody, err := json.Marshal(gin.H{
+			"token":    "avalidtoken",
+			"password": "short",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockPasswordService.AssertNotCalled(t, "ResetPassword")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockPasswordService := new(mocks.MockPasswordService)
+		mockPasswordService.On("ResetPassword", mock.AnythingOfType("*gin.Context"), "avalidtoken", "newpassword").Return(nil)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:               router,
+			PasswordService: mockPasswordService,
+		})
+
+		reqBody, err := json.Marshal(gin.H{
+			"token":    "avalidtoken",
+			"password": "newpassword",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockPasswordService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockErr := apperrors.NewAuthorization("Password reset token is invalid or has expired")
+
+		mockPasswordService := new(mocks.MockPasswordService)
+		mockPasswordService.On("ResetPassword", mock.AnythingOfType("*gin.Context"), "usedtoken", "newpassword").Return(mockErr)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:               router,
+			PasswordService: mockPasswordService,
+		})
+
+		reqBody, err := json.Marshal(gin.H{
+			"token":    "usedtoken",
+			"password": "newpassword",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+type signInReq struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,gte=6,lte=30"`
+}
+
+func (h *Handler) SignIn(c *gin.Context) {
+	var req signInReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u := &model.User{
+		Email:    req.Email,
+		Password: req.Password,
+	}
+
+	err := h.UserService.SignIn(c, u)
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to sign in user", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	h.signedIn(c, u)
+}
+
+// signedIn responds with the tokens of u, who passed their first
+// factor, or the challenge token of two-factor users
+func (h *Handler) signedIn(c *gin.Context, u *model.User) {
+	// the tokens of two-factor users wait for their code at /sign-in/2fa
+	if u.TOTPEnabled {
+		challengeToken, err := h.TwoFactorService.NewChallenge(c, u)
+
+		if err != nil {
+			logging.FromContext(c).Error("Failed to create two-factor challenge", "uid", u.UID, "err", err)
+
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"challengeToken": challengeToken,
+		})
+
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(c, u, "")
+
+	if err != nil {
+		logging.FromContext(c).Error("Failed to create tokens for user", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestSignIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// setup mock services, gin engine/router, handler layer
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("Bad request data", func(t *testing.T) {
+		rr := newRecorder(t)
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    "notanemail",
+			"password": "short",
+		})
+
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBuffer(reqBody))
+
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		mockUserService.AssertNotCalled(t, "SignIn")
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Error returned from UserService.SignIn", func(t *testing.T) {
+		email := "bob@bob.com"
+		password := "pwdoesnotmatch123"
+
+		mockUSArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			&model.User{Email: email, Password: password},
+		}
+
+		// so we can check for a known status code
+		mockError := apperrors.NewAuthorization("Invalid email and password combination")
+
+		mockUserService.On("SignIn", mockUSArgs...).Return(mockError)
+
+		rr := newRecorder(t)
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBuffer(reqBody))
+
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+
+		mockUserService.AssertCalled(t, "SignIn", mockUSArgs...)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Successful Token Creation", func(t *testing.T) {
+		email := "vuluu040320@gmail.com"
+		password := "SuperKeyPass123"
+
+		mockUSArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			&model.User{Email: email, Password: password},
+		}
+
+		mockUserService.On("SignIn", mockUSArgs...).Return(nil)
+
+		mockTSArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			&model.User{Email: email, Password: password},
+			"",
+		}
+
+		mockTokenPair := &model.TokenPair{
+			TokenID:      "idToken",
+			RefreshToken: "refreshToken",
+		}
+
+		mockTokenService.On("NewPairFromUser", mockTSArgs...).Return(mockTokenPair, nil)
+
+		rr := newRecorder(t)
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBuffer(reqBody))
+
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+
+		mockUserService.AssertCalled(t, "SignIn", mockUSArgs...)
+		mockTokenService.AssertCalled(t, "NewPairFromUser", mockTSArgs...)
+	})
+
+	t.Run("Failed Token Creation", func(t *testing.T) {
+		email := "cannotproducetoken@bob.com"
+		password := "cannotproducetoken"
+
+		mockUSArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			&model.User{Email: email, Password: password},
+		}
+
+		mockUserService.On("SignIn", mockUSArgs...).Return(nil)
+
+		mockTSArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			&model.User{Email: email, Password: password},
+			"",
+		}
+
+		mockError := apperrors.NewInternal()
+
+		mockTokenService.On("NewPairFromUser", mockTSArgs...).Return(nil, mockError)
+
+		rr := newRecorder(t)
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/sign-in", bytes.NewBuffer(reqBody))
+
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.NoError(t, err)
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+
+		mockUserService.AssertCalled(t, "SignIn", mockUSArgs...)
+		mockTokenService.AssertCalled(t, "NewPairFromUser", mockTSArgs...)
+	})
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// SignOut handler revokes every refresh token of the signed in user
+func (h *Handler) SignOut(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	uid := user.(*model.User).UID
+
+	if err := h.TokenService.Signout(c, uid); err != nil {
+		logging.FromContext(c).Error("Failed to sign out user", "uid", uid, "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "user signed out successfully!",
+	})
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestSignOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		ctxUser := &model.User{
+			UID:   uid,
+			Email: "vuluu040320@gmail.com",
+		}
+
+		// a response recorder for getting written http response
+		rr := newRecorder(t)
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("Signout", mock.AnythingOfType("*gin.Context"), ctxUser.UID).Return(nil)
+
+		// the client is signed in as ctxUser
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-out", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"message": "user signed out successfully!",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Signout Error", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		ctxUser := &model.User{
+			UID:   uid,
+			Email: "vuluu040320@gmail.com",
+		}
+
+		// a response recorder for getting written http response
+		rr := newRecorder(t)
+
+		mockError := apperrors.NewInternal()
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("Signout", mock.AnythingOfType("*gin.Context"), ctxUser.UID).Return(mockError)
+
+		// the client is signed in as ctxUser
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-out", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("No Authorization header", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+
+		rr := newRecorder(t)
+
+		// requests carry no ID token
+		router := gin.Default()
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sign-out", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNotCalled(t, "Signout", mock.Anything, mock.Anything)
+	})
+}
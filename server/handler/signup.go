@@ -1,10 +1,10 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
 	"github.com/vuluu2k/remember_fullstack/server/model"
 	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
 )
@@ -29,7 +29,7 @@ func (h *Handler) SignUp(c *gin.Context) {
 	err := h.UserService.SignUp(c, u)
 
 	if err != nil {
-		log.Printf("Failed to sign up user: %v \n", err.Error())
+		logging.FromContext(c).Error("Failed to sign up user", "err", err)
 
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
@@ -38,10 +38,15 @@ func (h *Handler) SignUp(c *gin.Context) {
 		return
 	}
 
+	// the account exists either way, so failing to email doesn't fail the sign up
+	if err := h.VerificationService.SendVerification(c, u); err != nil {
+		logging.FromContext(c).Error("Failed to send verification email", "uid", u.UID, "err", err)
+	}
+
 	tokens, err := h.TokenService.NewPairFromUser(c, u, "")
 
 	if err != nil {
-		log.Printf("Failed to sign up user: %v \n", err.Error())
+		logging.FromContext(c).Error("Failed to sign up user", "err", err)
 
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
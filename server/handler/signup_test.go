@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -22,7 +21,7 @@ func TestSignUp(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), mock.Anything).Return(nil)
 
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
 		router := gin.Default()
 
@@ -52,7 +51,7 @@ func TestSignUp(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), mock.Anything).Return(nil)
 
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
 		router := gin.Default()
 
@@ -83,7 +82,7 @@ func TestSignUp(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), mock.Anything).Return(nil)
 
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
 		router := gin.Default()
 
@@ -114,7 +113,7 @@ func TestSignUp(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), mock.Anything).Return(nil)
 
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
 		router := gin.Default()
 
@@ -151,7 +150,7 @@ func TestSignUp(t *testing.T) {
 
 		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), u).Return(apperrors.NewConflict("User Already Exists", u.Email))
 
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
 		router := gin.Default()
 
@@ -196,15 +195,18 @@ func TestSignUp(t *testing.T) {
 
 		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), u).Return(nil)
 		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*gin.Context"), u, "").Return(mockTokenResp, nil)
+		mockVerificationService := new(mocks.MockVerificationService)
+		mockVerificationService.On("SendVerification", mock.AnythingOfType("*gin.Context"), u).Return(nil)
 
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
 		router := gin.Default()
 
 		NewHandler(&Config{
-			R:            router,
-			UserService:  mockUserService,
-			TokenService: mockTokenService,
+			R:                   router,
+			UserService:         mockUserService,
+			TokenService:        mockTokenService,
+			VerificationService: mockVerificationService,
 		})
 
 		reqBody, err := json.Marshal(gin.H{
@@ -233,6 +235,7 @@ func TestSignUp(t *testing.T) {
 
 		mockUserService.AssertExpectations(t)
 		mockTokenService.AssertExpectations(t)
+		mockVerificationService.AssertExpectations(t)
 	})
 
 	t.Run("Failed Token Creation", func(t *testing.T) {
@@ -247,15 +250,18 @@ func TestSignUp(t *testing.T) {
 
 		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), u).Return(nil)
 		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*gin.Context"), u, "").Return(nil, mockErrorResponse)
+		mockVerificationService := new(mocks.MockVerificationService)
+		mockVerificationService.On("SendVerification", mock.AnythingOfType("*gin.Context"), u).Return(nil)
 
-		rr := httptest.NewRecorder()
+		rr := newRecorder(t)
 
 		router := gin.Default()
 
 		NewHandler(&Config{
-			R:            router,
-			UserService:  mockUserService,
-			TokenService: mockTokenService,
+			R:                   router,
+			UserService:         mockUserService,
+			TokenService:        mockTokenService,
+			VerificationService: mockVerificationService,
 		})
 
 		reqBody, err := json.Marshal(gin.H{
@@ -284,5 +290,57 @@ func TestSignUp(t *testing.T) {
 
 		mockUserService.AssertExpectations(t)
 		mockTokenService.AssertExpectations(t)
+		mockVerificationService.AssertExpectations(t)
+	})
+
+	t.Run("Verification email failure still signs up", func(t *testing.T) {
+		u := &model.User{
+			Email:    "vuluu040320@gmail.com",
+			Password: "SuperKeyPass123",
+		}
+
+		mockTokenResp := &model.TokenPair{
+			TokenID:      "tokenId",
+			RefreshToken: "refreshToken",
+		}
+
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockVerificationService := new(mocks.MockVerificationService)
+
+		mockUserService.On("SignUp", mock.AnythingOfType("*gin.Context"), u).Return(nil)
+		mockVerificationService.On("SendVerification", mock.AnythingOfType("*gin.Context"), u).Return(apperrors.NewInternal())
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*gin.Context"), u, "").Return(mockTokenResp, nil)
+
+		rr := newRecorder(t)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                   router,
+			UserService:         mockUserService,
+			TokenService:        mockTokenService,
+			VerificationService: mockVerificationService,
+		})
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    "vuluu040320@gmail.com",
+			"password": "SuperKeyPass123",
+		})
+
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/sign-up", bytes.NewBuffer(reqBody))
+
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		mockVerificationService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
 	})
 }
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+type tokensReq struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// Token handler consumes a refresh token and returns
+// a new id and refresh token pair
+func (h *Handler) Token(c *gin.Context) {
+	var req tokensReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	// verify refresh JWT
+	refreshToken, err := h.TokenService.ValidateRefreshToken(req.RefreshToken)
+
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// get up-to-date user
+	u, err := h.UserService.Get(c, refreshToken.UID)
+
+	if err != nil {
+		logging.FromContext(c).Error("Unable to find user for refresh token", "uid", refreshToken.UID, "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// create fresh pair of tokens, consuming the old refresh token
+	tokens, err := h.TokenService.NewPairFromUser(c, u, refreshToken.ID.String())
+
+	if err != nil {
+		logging.FromContext(c).Error("Failed to create tokens for user", "uid", u.UID, "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
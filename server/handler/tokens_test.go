@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		TokenService: mockTokenService,
+		UserService:  mockUserService,
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		rr := newRecorder(t)
+
+		// create a request body with invalid fields
+		reqBody, _ := json.Marshal(gin.H{
+			"notRefreshToken": "this key is not valid for this handler!",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockTokenService.AssertNotCalled(t, "ValidateRefreshToken")
+		mockUserService.AssertNotCalled(t, "Get")
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		for _, mockErrorResp := range []*apperrors.Error{
+			apperrors.NewAuthorization("Refresh token has expired"),
+			apperrors.NewAuthorization("Refresh token is malformed or invalid"),
+		} {
+			invalidTokenString := fmt.Sprintf("invalid: %v", mockErrorResp.Message)
+
+			mockTokenService.
+				On("ValidateRefreshToken", invalidTokenString).
+				Return(nil, mockErrorResp)
+
+			rr := newRecorder(t)
+
+			// create a request body with invalid fields
+			reqBody, _ := json.Marshal(gin.H{
+				"refreshToken": invalidTokenString,
+			})
+
+			request, _ := http.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(reqBody))
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			respBody, _ := json.Marshal(gin.H{
+				"error": mockErrorResp,
+			})
+
+			assert.Equal(t, mockErrorResp.Status(), rr.Code)
+			assert.Equal(t, respBody, rr.Body.Bytes())
+			mockTokenService.AssertCalled(t, "ValidateRefreshToken", invalidTokenString)
+		}
+
+		mockUserService.AssertNotCalled(t, "Get")
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Failure to create new token pair", func(t *testing.T) {
+		validTokenString := "valid"
+		mockTokenID, _ := uuid.NewRandom()
+		mockUserID, _ := uuid.NewRandom()
+
+		mockRefreshTokenResp := &model.RefreshToken{
+			SS:  validTokenString,
+			ID:  mockTokenID,
+			UID: mockUserID,
+		}
+
+		mockTokenService.
+			On("ValidateRefreshToken", validTokenString).
+			Return(mockRefreshTokenResp, nil)
+
+		mockUserResp := &model.User{
+			UID: mockUserID,
+		}
+
+		getArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			mockRefreshTokenResp.UID,
+		}
+
+		mockUserService.
+			On("Get", getArgs...).
+			Return(mockUserResp, nil)
+
+		mockError := apperrors.NewAuthorization("Refresh token has been revoked")
+
+		newPairArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			mockUserResp,
+			mockRefreshTokenResp.ID.String(),
+		}
+
+		mockTokenService.
+			On("NewPairFromUser", newPairArgs...).
+			Return(nil, mockError)
+
+		rr := newRecorder(t)
+
+		// create a request body with valid fields
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": validTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertCalled(t, "ValidateRefreshToken", validTokenString)
+		mockUserService.AssertCalled(t, "Get", getArgs...)
+		mockTokenService.AssertCalled(t, "NewPairFromUser", newPairArgs...)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		validTokenString := "anothervalid"
+		mockTokenID, _ := uuid.NewRandom()
+		mockUserID, _ := uuid.NewRandom()
+
+		mockRefreshTokenResp := &model.RefreshToken{
+			SS:  validTokenString,
+			ID:  mockTokenID,
+			UID: mockUserID,
+		}
+
+		mockTokenService.
+			On("ValidateRefreshToken", validTokenString).
+			Return(mockRefreshTokenResp, nil)
+
+		mockUserResp := &model.User{
+			UID: mockUserID,
+		}
+
+		getArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			mockRefreshTokenResp.UID,
+		}
+
+		mockUserService.
+			On("Get", getArgs...).
+			Return(mockUserResp, nil)
+
+		mockNewTokenID, _ := uuid.NewRandom()
+		mockNewUserID, _ := uuid.NewRandom()
+
+		mockTokenPairResp := &model.TokenPair{
+			TokenID:      "aNewIDToken",
+			RefreshToken: fmt.Sprintf("%v.%v", mockNewTokenID, mockNewUserID),
+		}
+
+		newPairArgs := mock.Arguments{
+			mock.AnythingOfType("*gin.Context"),
+			mockUserResp,
+			mockRefreshTokenResp.ID.String(),
+		}
+
+		mockTokenService.
+			On("NewPairFromUser", newPairArgs...).
+			Return(mockTokenPairResp, nil)
+
+		rr := newRecorder(t)
+
+		// create a request body with valid fields
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": validTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"tokens": mockTokenPairResp,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertCalled(t, "ValidateRefreshToken", validTokenString)
+		mockUserService.AssertCalled(t, "Get", getArgs...)
+		mockTokenService.AssertCalled(t, "NewPairFromUser", newPairArgs...)
+	})
+}
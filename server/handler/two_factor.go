@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// twoFactorCodeReq takes a code of the authenticator app or, where
+// accepted, a recovery code
+type twoFactorCodeReq struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type signInTwoFactorReq struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required,max=32"`
+}
+
+// SignInTwoFactor handler exchanges the challenge token from SignIn
+// and a code for the tokens of two-factor users
+func (h *Handler) SignInTwoFactor(c *gin.Context) {
+	var req signInTwoFactorReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u, err := h.TwoFactorService.VerifyChallenge(c, req.ChallengeToken, req.Code)
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to verify two-factor code", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(c, u, "")
+
+	if err != nil {
+		logging.FromContext(c).Error("Failed to create tokens for user", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// EnrollTwoFactor handler starts enrolling an authenticator app for the
+// signed in user
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	uid, ok := contextUID(c)
+
+	if !ok {
+		return
+	}
+
+	uri, err := h.TwoFactorService.Enroll(c, uid)
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to enroll two-factor authentication", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"uri": uri,
+	})
+}
+
+// ConfirmTwoFactor handler enables two-factor authentication with a
+// code of the enrolled app. The recovery codes are only shown here
+func (h *Handler) ConfirmTwoFactor(c *gin.Context) {
+	uid, ok := contextUID(c)
+
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	codes, err := h.TwoFactorService.Confirm(c, uid, req.Code)
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to confirm two-factor authentication", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": codes,
+	})
+}
+
+// DisableTwoFactor handler turns two-factor authentication off with a
+// code or recovery code
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	uid, ok := contextUID(c)
+
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	if err := h.TwoFactorService.Disable(c, uid, req.Code); err != nil {
+		logging.FromContext(c).Info("Failed to disable two-factor authentication", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication has been disabled",
+	})
+}
+
+// contextUID returns the uid of the signed in user, responding with an
+// error when there is none
+func contextUID(c *gin.Context) (uuid.UUID, bool) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return uuid.Nil, false
+	}
+
+	return user.(*model.User).UID, true
+}
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+// postJSON serves a POST of body to router through newRecorder
+func postJSON(t *testing.T, router *gin.Engine, path string, body gin.H) (int, []byte) {
+	t.Helper()
+
+	rr := newRecorder(t)
+
+	reqBody, err := json.Marshal(body)
+	assert.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(reqBody))
+	assert.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(rr, request)
+
+	return rr.Code, rr.Body.Bytes()
+}
+
+func TestSignInTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	email := "vuluu040320@gmail.com"
+	password := "SuperKeyPass123"
+
+	u := &model.User{
+		UID:         uid,
+		Email:       email,
+		TOTPEnabled: true,
+	}
+
+	t.Run("Password sign in returns a challenge", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			UserService:      mockUserService,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		mockUserService.On("SignIn", mock.AnythingOfType("*gin.Context"), &model.User{Email: email, Password: password}).
+			Run(func(args mock.Arguments) {
+				*args.Get(1).(*model.User) = *u
+			}).Return(nil)
+		mockTwoFactorService.On("NewChallenge", mock.AnythingOfType("*gin.Context"), u).Return("achallengetoken", nil)
+
+		code, body := postJSON(t, router, "/sign-in", gin.H{
+			"email":    email,
+			"password": password,
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"challengeToken": "achallengetoken",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, respBody, body)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Code is exchanged for tokens", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			UserService:      mockUserService,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		mockTokenPair := &model.TokenPair{
+			TokenID:      "idToken",
+			RefreshToken: "refreshToken",
+		}
+
+		mockTwoFactorService.On("VerifyChallenge", mock.AnythingOfType("*gin.Context"), "achallengetoken", "123456").Return(u, nil)
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*gin.Context"), u, "").Return(mockTokenPair, nil)
+
+		code, body := postJSON(t, router, "/sign-in/2fa", gin.H{
+			"challengeToken": "achallengetoken",
+			"code":           "123456",
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, respBody, body)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			UserService:      mockUserService,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		mockErr := apperrors.NewAuthorization("Invalid two-factor code")
+		mockTwoFactorService.On("VerifyChallenge", mock.AnythingOfType("*gin.Context"), "achallengetoken", "000000").Return(nil, mockErr)
+
+		code, body := postJSON(t, router, "/sign-in/2fa", gin.H{
+			"challengeToken": "achallengetoken",
+			"code":           "000000",
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, respBody, body)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Missing challenge", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                router,
+			UserService:      mockUserService,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		code, _ := postJSON(t, router, "/sign-in/2fa", gin.H{
+			"code": "123456",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		mockTwoFactorService.AssertNotCalled(t, "VerifyChallenge")
+	})
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	t.Run("Enroll", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		uri := "otpauth://totp/Remember:vuluu040320@gmail.com?secret=JBSWY3DPEHPK3PXP"
+		mockTwoFactorService.On("Enroll", mock.AnythingOfType("*gin.Context"), uid).Return(uri, nil)
+
+		code, body := postJSON(t, router, "/2fa/enroll", gin.H{})
+
+		respBody, err := json.Marshal(gin.H{
+			"uri": uri,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, respBody, body)
+	})
+
+	t.Run("Confirm returns recovery codes", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		codes := []string{"ABCDE-FGHIJ", "KLMNO-PQRST"}
+		mockTwoFactorService.On("Confirm", mock.AnythingOfType("*gin.Context"), uid, "123456").Return(codes, nil)
+
+		code, body := postJSON(t, router, "/2fa/confirm", gin.H{
+			"code": "123456",
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"recoveryCodes": codes,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, respBody, body)
+	})
+
+	t.Run("Confirm without a code", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		code, _ := postJSON(t, router, "/2fa/confirm", gin.H{})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		mockTwoFactorService.AssertNotCalled(t, "Confirm")
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		mockTwoFactorService.On("Disable", mock.AnythingOfType("*gin.Context"), uid, "ABCDE-FGHIJ").Return(nil)
+
+		code, _ := postJSON(t, router, "/2fa/disable", gin.H{
+			"code": "ABCDE-FGHIJ",
+		})
+
+		assert.Equal(t, http.StatusOK, code)
+		mockTwoFactorService.AssertExpectations(t)
+	})
+
+	t.Run("Disable with a wrong code", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		mockTokenService := new(mocks.MockTokenService)
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                router,
+			TokenService:     mockTokenService,
+			TwoFactorService: mockTwoFactorService,
+		})
+
+		mockErr := apperrors.NewAuthorization("Invalid two-factor code")
+		mockTwoFactorService.On("Disable", mock.AnythingOfType("*gin.Context"), uid, "000000").Return(mockErr)
+
+		code, _ := postJSON(t, router, "/2fa/disable", gin.H{
+			"code": "000000",
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// verifyEmailReq is read from the query of GET requests, which is
+// how links from verification emails arrive, or the body of POSTs
+type verifyEmailReq struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// VerifyEmail handler confirms the email of the user a verification
+// token was sent to
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u, err := h.VerificationService.VerifyEmail(c, req.Token)
+
+	if err != nil {
+		logging.FromContext(c).Info("Failed to verify email", "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": u.Public(),
+	})
+}
+
+// ResendVerification handler emails the signed in user a new link
+// verifying their email
+func (h *Handler) ResendVerification(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		logging.FromContext(c).Error("Unable to extract user from request context for unknown reason")
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// the user in the ID token may predate verifying or changing the email
+	u, err := h.UserService.Get(c, user.(*model.User).UID)
+
+	if err != nil {
+		logging.FromContext(c).Error("Unable to find user", "uid", user.(*model.User).UID, "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if u.EmailVerified {
+		err := apperrors.NewBadRequest("Your email is already verified")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if err := h.VerificationService.SendVerification(c, u); err != nil {
+		logging.FromContext(c).Error("Failed to send verification email", "uid", u.UID, "err", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "A link to verify your email has been sent to " + u.Email,
+	})
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	mockUser := &model.User{
+		UID:           uid,
+		Email:         "vuluu040320@gmail.com",
+		Password:      "hashed",
+		EmailVerified: true,
+	}
+
+	t.Run("GET with token in query", func(t *testing.T) {
+		mockVerificationService := new(mocks.MockVerificationService)
+		mockVerificationService.On("VerifyEmail", mock.AnythingOfType("*gin.Context"), "avalidtoken").Return(mockUser, nil)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                   router,
+			VerificationService: mockVerificationService,
+		})
+
+		request, err := http.NewRequest(http.MethodGet, "/verify-email?token=avalidtoken", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"user": mockUser.Public(),
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Contains(t, rr.Body.String(), `"email_verified":true`)
+		mockVerificationService.AssertExpectations(t)
+	})
+
+	t.Run("POST with token in body", func(t *testing.T) {
+		mockVerificationService := new(mocks.MockVerificationService)
+		mockVerificationService.On("VerifyEmail", mock.AnythingOfType("*gin.Context"), "avalidtoken").Return(mockUser, nil)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                   router,
+			VerificationService: mockVerificationService,
+		})
+
+		reqBody, err := json.Marshal(gin.H{
+			"token": "avalidtoken",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/verify-email", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockVerificationService.AssertExpectations(t)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		mockVerificationService := new(mocks.MockVerificationService)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                   router,
+			VerificationService: mockVerificationService,
+		})
+
+		request, err := http.NewRequest(http.MethodGet, "/verify-email", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockVerificationService.AssertNotCalled(t, "VerifyEmail")
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockErr := apperrors.NewAuthorization("Verification token is invalid or has expired")
+
+		mockVerificationService := new(mocks.MockVerificationService)
+		mockVerificationService.On("VerifyEmail", mock.AnythingOfType("*gin.Context"), "aninvalidtoken").Return(nil, mockErr)
+
+		rr := newRecorder(t)
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:                   router,
+			VerificationService: mockVerificationService,
+		})
+
+		request, err := http.NewRequest(http.MethodGet, "/verify-email?token=aninvalidtoken", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestResendVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	// a stale ID token still claiming a verified email
+	ctxUser := &model.User{
+		UID:           uid,
+		Email:         "vuluu040320@gmail.com",
+		EmailVerified: true,
+	}
+
+	resend := func(t *testing.T, router *gin.Engine) *httptest.ResponseRecorder {
+		t.Helper()
+
+		rr := newRecorder(t)
+
+		request, err := http.NewRequest(http.MethodPost, "/verify-email/resend", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Sends a new link", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockUserService := new(mocks.MockUserService)
+		mockVerificationService := new(mocks.MockVerificationService)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                   router,
+			UserService:         mockUserService,
+			TokenService:        mockTokenService,
+			VerificationService: mockVerificationService,
+		})
+
+		u := &model.User{UID: uid, Email: "new@remember.test"}
+
+		mockUserService.On("Get", mock.AnythingOfType("*gin.Context"), uid).Return(u, nil)
+		mockVerificationService.On("SendVerification", mock.AnythingOfType("*gin.Context"), u).Return(nil)
+
+		rr := resend(t, router)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"message":"A link to verify your email has been sent to new@remember.test"}`, rr.Body.String())
+		mockVerificationService.AssertExpectations(t)
+	})
+
+	t.Run("Already verified", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockUserService := new(mocks.MockUserService)
+		mockVerificationService := new(mocks.MockVerificationService)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                   router,
+			UserService:         mockUserService,
+			TokenService:        mockTokenService,
+			VerificationService: mockVerificationService,
+		})
+
+		mockUserService.On("Get", mock.AnythingOfType("*gin.Context"), uid).Return(ctxUser, nil)
+
+		rr := resend(t, router)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockVerificationService.AssertNotCalled(t, "SendVerification", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Sending fails", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockUserService := new(mocks.MockUserService)
+		mockVerificationService := new(mocks.MockVerificationService)
+
+		router := authedRouter(mockTokenService, ctxUser)
+
+		NewHandler(&Config{
+			R:                   router,
+			UserService:         mockUserService,
+			TokenService:        mockTokenService,
+			VerificationService: mockVerificationService,
+		})
+
+		u := &model.User{UID: uid, Email: "new@remember.test"}
+
+		mockUserService.On("Get", mock.AnythingOfType("*gin.Context"), uid).Return(u, nil)
+		mockVerificationService.On("SendVerification", mock.AnythingOfType("*gin.Context"), u).Return(apperrors.NewInternal())
+
+		rr := resend(t, router)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
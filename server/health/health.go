@@ -0,0 +1,184 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State of the server as reported by Readyz
+type State int32
+
+const (
+	// Starting until data sources are connected and injected
+	Starting State = iota
+	// Ready while serving traffic
+	Ready
+	// Draining once a shutdown signal was received
+	Draining
+)
+
+func (s State) String() string {
+	switch s {
+	case Starting:
+		return "starting"
+	case Ready:
+		return "ready"
+	case Draining:
+		return "draining"
+	default:
+		return "unknown"
+	}
+}
+
+// Check reports whether a dependency is usable. It must return once
+// ctx is done
+type Check func(ctx context.Context) error
+
+// Component is the outcome of a single Check
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the body of Readyz responses
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Probe answers liveness and readiness probes. It starts out in the
+// Starting state with no checks
+type Probe struct {
+	timeout time.Duration
+	state   atomic.Int32
+
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// NewProbe creates a Probe giving each check timeout to complete
+func NewProbe(timeout time.Duration) *Probe {
+	return &Probe{
+		timeout: timeout,
+	}
+}
+
+// SetChecks replaces the checks run by Readyz, keyed by component name
+func (p *Probe) SetChecks(checks map[string]Check) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.checks = checks
+}
+
+// SetState moves the probe to s
+func (p *Probe) SetState(s State) {
+	p.state.Store(int32(s))
+}
+
+// State returns the current state of the probe
+func (p *Probe) State() State {
+	return State(p.state.Load())
+}
+
+// Healthz reports that the process is alive
+func (p *Probe) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Report{Status: "ok"})
+}
+
+// Readyz reports whether the server should receive traffic. It
+// responds 503 while starting or draining, or if any check fails,
+// with the status of every component
+func (p *Probe) Readyz(w http.ResponseWriter, r *http.Request) {
+	state := p.State()
+
+	if state != Ready {
+		writeJSON(w, http.StatusServiceUnavailable, Report{Status: state.String()})
+		return
+	}
+
+	report := p.run(r.Context())
+	status := http.StatusOK
+
+	if report.Status != state.String() {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, report)
+}
+
+// run executes every check concurrently
+func (p *Probe) run(ctx context.Context) Report {
+	p.mu.RLock()
+	checks := p.checks
+	p.mu.RUnlock()
+
+	report := Report{
+		Status:     Ready.String(),
+		Components: make(map[string]Component, len(checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for name, check := range checks {
+		wg.Add(1)
+
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			component := p.runCheck(ctx, name, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Components[name] = component
+
+			if component.Status != "up" {
+				report.Status = "unavailable"
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+func (p *Probe) runCheck(ctx context.Context, name string, check Check) Component {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := check(ctx)
+
+	if err == nil {
+		return Component{Status: "up"}
+	}
+
+	slog.Warn("Readiness check failed", "component", name, "err", err)
+
+	// the cause is logged, not exposed
+	if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+		return Component{Status: "down", Error: "timeout"}
+	}
+
+	return Component{Status: "down", Error: "unreachable"}
+}
+
+func writeJSON(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("Unable to write health report", "err", err)
+	}
+}
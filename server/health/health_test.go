@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readyz(t *testing.T, p *Probe) (int, Report) {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	p.Readyz(rr, request)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+
+	return rr.Code, report
+}
+
+func TestHealthz(t *testing.T) {
+	p := NewProbe(time.Second)
+
+	rr := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	p.Healthz(rr, request)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+
+	t.Run("Starting", func(t *testing.T) {
+		p := NewProbe(time.Second)
+
+		code, report := readyz(t, p)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "starting", report.Status)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		p := NewProbe(time.Second)
+		p.SetChecks(map[string]Check{"postgres": up, "redis": up})
+		p.SetState(Ready)
+
+		code, report := readyz(t, p)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", report.Status)
+		assert.Equal(t, map[string]Component{
+			"postgres": {Status: "up"},
+			"redis":    {Status: "up"},
+		}, report.Components)
+	})
+
+	t.Run("Failing and slow dependencies", func(t *testing.T) {
+		p := NewProbe(20 * time.Millisecond)
+		p.SetChecks(map[string]Check{
+			"postgres": up,
+			"redis": func(ctx context.Context) error {
+				return errors.New("dial tcp 10.0.0.3:6379: connection refused")
+			},
+			"storage": func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+		p.SetState(Ready)
+
+		code, report := readyz(t, p)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "unavailable", report.Status)
+		assert.Equal(t, map[string]Component{
+			"postgres": {Status: "up"},
+			"redis":    {Status: "down", Error: "unreachable"},
+			"storage":  {Status: "down", Error: "timeout"},
+		}, report.Components)
+	})
+
+	t.Run("Draining", func(t *testing.T) {
+		p := NewProbe(time.Second)
+		p.SetChecks(map[string]Check{"postgres": up})
+		p.SetState(Draining)
+
+		code, report := readyz(t, p)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "draining", report.Status)
+		assert.Empty(t, report.Components)
+	})
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vuluu2k/remember_fullstack/server/config"
+	"github.com/vuluu2k/remember_fullstack/server/handler"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/mailer"
+	"github.com/vuluu2k/remember_fullstack/server/metrics"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/repository"
+	"github.com/vuluu2k/remember_fullstack/server/service"
+)
+
+// inject builds the repositories, services and handlers on top of
+// the data sources and returns the router serving them, along with a
+// wait for the work services still do after responding. It fails if
+// any dependency is missing rather than booting into a server whose
+// calls panic
+func inject(d *dataSources, cfg *config.Config) (*gin.Engine, func(ctx context.Context) error, error) {
+	slog.Info("Injecting data sources")
+
+	if d == nil || d.DB == nil || d.RedisClient == nil || (d.ImageDir == "" && d.S3Client == nil) {
+		return nil, nil, errors.New("all data sources must be initialized before injecting")
+	}
+
+	/*
+	 * repository layer
+	 */
+	userRepository := repository.NewUserRepository(d.DB)
+	tokenRepository := repository.NewTokenRepository(d.RedisClient)
+	imageRepository := newImageRepository(d, cfg)
+	rateLimitRepository := repository.NewRateLimitRepository(d.RedisClient)
+	identityRepository := repository.NewIdentityRepository(d.DB)
+
+	/*
+	 * service layer
+	 */
+	hashParams := service.HashParams{
+		N: int(cfg.Hash.ScryptN),
+		R: int(cfg.Hash.ScryptR),
+		P: int(cfg.Hash.ScryptP),
+	}
+
+	if err := hashParams.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid password hash costs: %w", err)
+	}
+
+	// wrong passwords and two-factor codes count towards the same lockout
+	lockout := service.LockoutPolicy{
+		Threshold:    int(cfg.Lockout.Threshold),
+		BaseDuration: time.Duration(cfg.Lockout.BaseSecs) * time.Second,
+		MaxDuration:  time.Duration(cfg.Lockout.MaxSecs) * time.Second,
+	}
+
+	userService := service.NewUserService(&service.USConfig{
+		UserRepository:  userRepository,
+		ImageRepository: imageRepository,
+		Lockout:         lockout,
+		Hash:            hashParams,
+	})
+
+	twoFactorService := service.NewTwoFactorService(&service.TFSConfig{
+		UserRepository:          userRepository,
+		Lockout:                 lockout,
+		Issuer:                  cfg.Token.TOTPIssuer,
+		ChallengeSecret:         cfg.Token.ChallengeSecret,
+		ChallengeExpirationSecs: cfg.Token.ChallengeExpirationSecs,
+	})
+
+	// load rsa keys
+	priv, err := os.ReadFile(cfg.Token.PrivKeyFile)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read private key pem file: %w", err)
+	}
+
+	privKey, err := jwt.ParseRSAPrivateKeyFromPEM(priv)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse private key: %w", err)
+	}
+
+	pub, err := os.ReadFile(cfg.Token.PubKeyFile)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read public key pem file: %w", err)
+	}
+
+	pubKey, err := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse public key: %w", err)
+	}
+
+	tokenService := service.NewTokenService(&service.TSConfig{
+		TokenRepository:       tokenRepository,
+		PrivKey:               privKey,
+		PubKey:                pubKey,
+		RefreshSecret:         cfg.Token.RefreshSecret,
+		IDExpirationSecs:      cfg.Token.IDExpirationSecs,
+		RefreshExpirationSecs: cfg.Token.RefreshExpirationSecs,
+	})
+
+	mailSender, err := newMailer(cfg)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verificationService := service.NewVerificationService(&service.VSConfig{
+		UserRepository:  userRepository,
+		TokenRepository: tokenRepository,
+		Mailer:          mailSender,
+		Secret:          cfg.Token.VerifySecret,
+		ExpirationSecs:  cfg.Token.VerifyExpirationSecs,
+		VerifyURL:       cfg.Mail.VerifyURL,
+	})
+
+	passwordService := service.NewPasswordService(&service.PSConfig{
+		UserRepository:  userRepository,
+		TokenRepository: tokenRepository,
+		Mailer:          mailSender,
+		ExpirationSecs:  cfg.Token.ResetExpirationSecs,
+		ResetURL:        cfg.Mail.ResetURL,
+		Hash:            hashParams,
+	})
+
+	oidcService, err := newOIDCService(cfg, userRepository, identityRepository, tokenRepository)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	/*
+	 * handler layer
+	 */
+	// initialize gin.Engine
+	router := gin.New()
+	router.ContextWithFallback = true
+
+	// no trusted proxies makes the client IP the remote address
+	var trustedProxies []string
+
+	for _, proxy := range strings.Split(cfg.Server.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	router.Use(logging.Middleware(), metrics.Middleware(), gin.Recovery())
+
+	// local profile images are served straight from the image directory,
+	// the bucket serves them itself
+	if d.S3Client == nil {
+		router.Static(cfg.Storage.ImageURL, d.ImageDir)
+	}
+
+	handler.NewHandler(&handler.Config{
+		R:                   router,
+		UserService:         userService,
+		TokenService:        tokenService,
+		VerificationService: verificationService,
+		PasswordService:     passwordService,
+		TwoFactorService:    twoFactorService,
+		OIDCService:         oidcService,
+		BaseURL:             cfg.Server.AuthAPIURL,
+		MaxBodyBytes:        cfg.Server.MaxBodyBytes,
+
+		RateLimitRepository: rateLimitRepository,
+		IPRateLimit: model.RateLimit{
+			Burst:    cfg.RateLimit.IPBurst,
+			Interval: time.Duration(cfg.RateLimit.IPRefillSecs) * time.Second,
+		},
+		EmailRateLimit: model.RateLimit{
+			Burst:    cfg.RateLimit.EmailBurst,
+			Interval: time.Duration(cfg.RateLimit.EmailRefillSecs) * time.Second,
+		},
+	})
+
+	return router, passwordService.Wait, nil
+}
+
+// newImageRepository stores images in the storage prepared by initDS
+func newImageRepository(d *dataSources, cfg *config.Config) model.ImageRepository {
+	if d.S3Client != nil {
+		return repository.NewS3ImageRepository(d.S3Client, d.S3Bucket, strings.TrimSuffix(cfg.Storage.ImageURL, "/"))
+	}
+
+	return repository.NewImageRepository(d.ImageDir, cfg.Storage.ImageURL)
+}
+
+// newMailer picks the Mailer named by cfg.Mail.Mailer
+func newMailer(cfg *config.Config) (model.Mailer, error) {
+	m := cfg.Mail
+
+	switch m.Mailer {
+	case "smtp":
+		if m.SMTPHost == "" {
+			return nil, errors.New("SMTP_HOST is required by the smtp mailer")
+		}
+
+		return mailer.NewSMTPMailer(m.SMTPHost, m.SMTPPort, m.SMTPUser, m.SMTPPassword, m.From), nil
+	case "file":
+		return mailer.NewFileMailer(m.Dir, m.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q, expected smtp or file", m.Mailer)
+	}
+}
+
+// newOIDCService discovers the identity provider of cfg.OIDC, it
+// returns nil when none is configured
+func newOIDCService(cfg *config.Config, users model.UserRepository, identities model.IdentityRepository, tokens model.TokenRepository) (model.OIDCService, error) {
+	o := cfg.OIDC
+
+	if o.Issuer == "" {
+		return nil, nil
+	}
+
+	if o.Provider == "" || o.ClientID == "" || o.RedirectURL == "" {
+		return nil, errors.New("OIDC_PROVIDER, OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.StartupTimeoutSecs)*time.Second)
+	defer cancel()
+
+	provider, err := service.NewOIDCProvider(ctx, &service.OIDCProviderConfig{
+		Name:         o.Provider,
+		Issuer:       o.Issuer,
+		ClientID:     o.ClientID,
+		ClientSecret: o.ClientSecret,
+		RedirectURL:  o.RedirectURL,
+		Scopes:       strings.Fields(o.Scopes),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewOIDCService(&service.OSConfig{
+		UserRepository:      users,
+		IdentityRepository:  identities,
+		TokenRepository:     tokens,
+		Providers:           []*service.OIDCProvider{provider},
+		StateExpirationSecs: o.StateExpirationSecs,
+	}), nil
+}
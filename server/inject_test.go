@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/config"
+)
+
+// writeKeyPair writes an rsa key pair the way make create-keypair does
+func writeKeyPair(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	privFile := filepath.Join(dir, "rsa_private_test.pem")
+	pubFile := filepath.Join(dir, "rsa_public_test.pem")
+
+	require.NoError(t, os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600))
+	require.NoError(t, os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0600))
+
+	return privFile, pubFile
+}
+
+func TestInject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	privFile, pubFile := writeKeyPair(t, dir)
+
+	cfg := &config.Config{
+		Server: config.Server{
+			AuthAPIURL:   "/api/account",
+			MaxBodyBytes: 1024,
+		},
+		Token: config.Token{
+			PrivKeyFile:             privFile,
+			PubKeyFile:              pubFile,
+			RefreshSecret:           "anotsorandomtestsecret",
+			IDExpirationSecs:        900,
+			RefreshExpirationSecs:   3600,
+			VerifySecret:            "anotherunrandomtestsecret",
+			VerifyExpirationSecs:    3600,
+			ResetExpirationSecs:     3600,
+			ChallengeSecret:         "yetanotherunrandomtestsecret",
+			ChallengeExpirationSecs: 300,
+			TOTPIssuer:              "Remember",
+		},
+		Storage: config.Storage{
+			ImageDir: dir,
+			ImageURL: "/api/account/images",
+		},
+		Mail: config.Mail{
+			Mailer:    "file",
+			Dir:       filepath.Join(dir, "mail"),
+			From:      "Remember <no-reply@remember.test>",
+			VerifyURL: "http://dev2000.test/api/account/verify-email",
+			ResetURL:  "http://dev2000.test/reset-password",
+		},
+	}
+
+	// neither of these connects until used
+	db, err := sqlx.Open("postgres", "host=localhost")
+	require.NoError(t, err)
+
+	ds := &dataSources{
+		DB:          db,
+		RedisClient: redis.NewClient(&redis.Options{Addr: "localhost:0"}),
+		ImageDir:    dir,
+	}
+
+	t.Cleanup(func() {
+		ds.close()
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		router, _, err := inject(ds, cfg)
+
+		require.NoError(t, err)
+
+		routes := map[string]bool{}
+
+		for _, r := range router.Routes() {
+			routes[r.Method+" "+r.Path] = true
+		}
+
+		assert.True(t, routes[http.MethodGet+" /api/account/me"])
+		assert.True(t, routes[http.MethodPost+" /api/account/sign-up"])
+		assert.True(t, routes[http.MethodPost+" /api/account/token"])
+		assert.True(t, routes[http.MethodGet+" /api/account/verify-email"])
+		assert.True(t, routes[http.MethodPost+" /api/account/verify-email/resend"])
+		assert.True(t, routes[http.MethodPost+" /api/account/password/forgot"])
+		assert.True(t, routes[http.MethodPost+" /api/account/password/reset"])
+		assert.True(t, routes[http.MethodPost+" /api/account/sign-in/2fa"])
+		assert.True(t, routes[http.MethodPost+" /api/account/2fa/enroll"])
+
+		// without an OIDC_ISSUER
+		assert.False(t, routes[http.MethodGet+" /api/account/oidc/:provider/login"])
+
+		assert.True(t, routes[http.MethodGet+" /api/account/images/*filepath"])
+	})
+
+	t.Run("S3 image storage", func(t *testing.T) {
+		// doesn't connect until used either
+		client, err := minio.New("localhost:0", &minio.Options{})
+		require.NoError(t, err)
+
+		withS3 := *ds
+		withS3.ImageDir = ""
+		withS3.S3Client = client
+		withS3.S3Bucket = "profile-images"
+
+		router, _, err := inject(&withS3, cfg)
+		require.NoError(t, err)
+
+		for _, r := range router.Routes() {
+			assert.NotEqual(t, "/api/account/images/*filepath", r.Path)
+		}
+	})
+
+	t.Run("OIDC provider", func(t *testing.T) {
+		var issuer string
+
+		idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/.well-known/openid-configuration" {
+				http.NotFound(w, r)
+				return
+			}
+
+			fmt.Fprintf(w, `{"issuer":%q,"authorization_endpoint":%q,"token_endpoint":%q,"jwks_uri":%q}`,
+				issuer, issuer+"/authorize", issuer+"/token", issuer+"/jwks")
+		}))
+		t.Cleanup(idp.Close)
+		issuer = idp.URL
+
+		withOIDC := *cfg
+		withOIDC.Server.StartupTimeoutSecs = 5
+		withOIDC.OIDC = config.OIDC{
+			Provider:            "idp",
+			Issuer:              issuer,
+			ClientID:            "remember",
+			RedirectURL:         "http://dev2000.test/api/account/oidc/idp/callback",
+			Scopes:              "openid email",
+			StateExpirationSecs: 600,
+		}
+
+		router, _, err := inject(ds, &withOIDC)
+		require.NoError(t, err)
+
+		routes := map[string]bool{}
+
+		for _, r := range router.Routes() {
+			routes[r.Method+" "+r.Path] = true
+		}
+
+		assert.True(t, routes[http.MethodGet+" /api/account/oidc/:provider/login"])
+		assert.True(t, routes[http.MethodGet+" /api/account/oidc/:provider/callback"])
+
+		withOIDC.OIDC.Issuer = issuer + "/missing"
+
+		_, _, err = inject(ds, &withOIDC)
+		assert.ErrorContains(t, err, "unable to discover OIDC provider idp")
+
+		withOIDC.OIDC.ClientID = ""
+
+		_, _, err = inject(ds, &withOIDC)
+		assert.EqualError(t, err, "OIDC_PROVIDER, OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	})
+
+	t.Run("Missing data sources", func(t *testing.T) {
+		_, _, err := inject(&dataSources{DB: db}, cfg)
+		assert.Error(t, err)
+
+		_, _, err = inject(nil, cfg)
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing keys", func(t *testing.T) {
+		missingKeys := *cfg
+		missingKeys.Token.PrivKeyFile = filepath.Join(dir, "missing.pem")
+
+		_, _, err := inject(ds, &missingKeys)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid hash costs", func(t *testing.T) {
+		invalidHash := *cfg
+		invalidHash.Hash.ScryptN = 1000
+
+		_, _, err := inject(ds, &invalidHash)
+		assert.ErrorContains(t, err, "invalid password hash costs")
+	})
+
+	t.Run("Unknown mailer", func(t *testing.T) {
+		unknownMailer := *cfg
+		unknownMailer.Mail.Mailer = "pigeon"
+
+		_, _, err := inject(ds, &unknownMailer)
+		assert.EqualError(t, err, `unknown mailer "pigeon", expected smtp or file`)
+
+		unknownMailer.Mail.Mailer = "smtp"
+
+		_, _, err = inject(ds, &unknownMailer)
+		assert.EqualError(t, err, "SMTP_HOST is required by the smtp mailer")
+	})
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// redacted replaces the value of sensitive attributes
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys, lower cased and without separators,
+// whose values are never written
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"idtoken":       true,
+	"refreshtoken":  true,
+	"token":         true,
+	"tokenstring":   true,
+	"ss":            true,
+}
+
+// isSensitive reports whether the value of an attribute named key
+// must be redacted
+func isSensitive(key string) bool {
+	k := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+
+	return sensitiveKeys[k] || strings.Contains(k, "password") || strings.Contains(k, "secret")
+}
+
+// redact is a slog ReplaceAttr func hiding sensitive values
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	return a
+}
+
+// New creates a JSON logger writing to w which redacts passwords,
+// tokens and secrets automatically
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying l
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the request scoped logger stored in ctx by
+// Middleware, or the default logger outside of a request
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return l
+		}
+	}
+
+	return slog.Default()
+}
+
+// With adds attrs to the logger carried by ctx
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Redacts sensitive attributes", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, slog.LevelInfo)
+
+		logger.Info("signing in",
+			"email", "bob@bob.com",
+			"password", "avalidpassword",
+			"new_password", "anothervalidpassword",
+			"refreshToken", "a.refresh.token",
+			"Authorization", "Bearer an.id.token",
+			"refresh_secret", "shh",
+		)
+
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+		assert.Equal(t, "bob@bob.com", entry["email"])
+		assert.Equal(t, redacted, entry["password"])
+		assert.Equal(t, redacted, entry["new_password"])
+		assert.Equal(t, redacted, entry["refreshToken"])
+		assert.Equal(t, redacted, entry["Authorization"])
+		assert.Equal(t, redacted, entry["refresh_secret"])
+		assert.NotContains(t, buf.String(), "avalidpassword")
+	})
+
+	t.Run("Respects level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, slog.LevelWarn)
+
+		logger.Info("dropped")
+		assert.Empty(t, buf.String())
+
+		logger.Warn("kept")
+		assert.Contains(t, buf.String(), "kept")
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("Falls back to default logger", func(t *testing.T) {
+		assert.Equal(t, slog.Default(), FromContext(context.Background()))
+	})
+
+	t.Run("Returns stored logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, slog.LevelInfo)
+
+		ctx := With(WithLogger(context.Background(), logger), "uid", "abc")
+		FromContext(ctx).Info("hello")
+
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "abc", entry["uid"])
+	})
+}
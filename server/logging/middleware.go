@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID of a request across services
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits propagated request IDs to something safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Middleware assigns every request an ID, propagating a valid incoming
+// X-Request-ID, and stores a logger carrying it in the request context
+// for handlers, services and repositories. Once the request is handled
+// it logs the route, status and latency. Middleware that authenticates
+// the user is expected to add the UID to the request's logger with With
+//
+// The engine must have ContextWithFallback enabled for the logger to be
+// found through a *gin.Context
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Header(RequestIDHeader, requestID)
+
+		logger := FromContext(c.Request.Context()).With("request_id", requestID)
+		c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		attrs := []any{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		// the request's logger carries the uid once AuthUser ran
+		FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request handled", attrs...)
+	}
+}
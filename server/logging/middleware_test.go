@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	entries := func(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+		var res []map[string]interface{}
+		scanner := bufio.NewScanner(buf)
+
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			res = append(res, entry)
+		}
+
+		return res
+	}
+
+	t.Run("Generates request ID", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		router := gin.New()
+		router.ContextWithFallback = true
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), New(buf, slog.LevelInfo)))
+		}, Middleware())
+
+		router.GET("/users/:id", func(c *gin.Context) {
+			FromContext(c).Info("in handler")
+			c.Status(http.StatusTeapot)
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		router.ServeHTTP(rr, request)
+
+		requestID := rr.Header().Get(RequestIDHeader)
+		assert.NotEmpty(t, requestID)
+
+		logged := entries(t, buf)
+		assert.Len(t, logged, 2)
+
+		assert.Equal(t, "in handler", logged[0]["msg"])
+		assert.Equal(t, requestID, logged[0]["request_id"])
+
+		assert.Equal(t, requestID, logged[1]["request_id"])
+		assert.Equal(t, "/users/:id", logged[1]["route"])
+		assert.Equal(t, float64(http.StatusTeapot), logged[1]["status"])
+		assert.Equal(t, "WARN", logged[1]["level"])
+	})
+
+	t.Run("Propagates valid request ID", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		router := gin.New()
+		router.ContextWithFallback = true
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), New(buf, slog.LevelInfo)))
+		}, Middleware())
+
+		router.GET("/users/:id", func(c *gin.Context) {
+			FromContext(c).Info("in handler")
+			c.Status(http.StatusTeapot)
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		request.Header.Set(RequestIDHeader, "upstream-id.1")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, "upstream-id.1", rr.Header().Get(RequestIDHeader))
+
+		for _, entry := range entries(t, buf) {
+			assert.Equal(t, "upstream-id.1", entry["request_id"])
+		}
+	})
+
+	t.Run("Replaces invalid request ID", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		router := gin.New()
+		router.ContextWithFallback = true
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), New(buf, slog.LevelInfo)))
+		}, Middleware())
+
+		router.GET("/users/:id", func(c *gin.Context) {
+			FromContext(c).Info("in handler")
+			c.Status(http.StatusTeapot)
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		request.Header.Set(RequestIDHeader, "bad id\nforged=1")
+		router.ServeHTTP(rr, request)
+
+		requestID := rr.Header().Get(RequestIDHeader)
+		assert.NotEmpty(t, requestID)
+		assert.NotEqual(t, "bad id\nforged=1", requestID)
+	})
+}
@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// fileMailer writes every email to a .eml file in Dir instead of
+// sending it, for local development
+type fileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer is a factory for initializing file Mailers
+func NewFileMailer(dir string, from string) model.Mailer {
+	return &fileMailer{
+		Dir:  dir,
+		From: from,
+	}
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg *model.Message) error {
+	now := time.Now()
+
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		logging.FromContext(ctx).Error("Unable to create mail directory", "dir", m.Dir, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	name := filepath.Join(m.Dir, fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405Z"), uuid.NewString()))
+
+	if err := os.WriteFile(name, format(m.From, msg, now), 0600); err != nil {
+		logging.FromContext(ctx).Error("Unable to write email", "file", name, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	logging.FromContext(ctx).Info("Wrote email", "file", name)
+
+	return nil
+}
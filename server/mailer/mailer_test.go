@@ -0,0 +1,161 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+var msg = &model.Message{
+	To:      "bob@bob.com",
+	Subject: "Verify your email",
+	Body:    "Hello Bob,\nopen the link.\n",
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("Headers and body", func(t *testing.T) {
+		raw := string(format("Remember <no-reply@remember.test>", msg, time.Now()))
+
+		assert.Contains(t, raw, "From: Remember <no-reply@remember.test>\r\n")
+		assert.Contains(t, raw, "To: bob@bob.com\r\n")
+		assert.Contains(t, raw, "Subject: Verify your email\r\n")
+		assert.True(t, strings.HasSuffix(raw, "\r\n\r\nHello Bob,\r\nopen the link.\r\n"))
+	})
+
+	t.Run("Strips header injection", func(t *testing.T) {
+		raw := string(format("no-reply@remember.test", &model.Message{
+			To:      "bob@bob.com\r\nBcc: eve@eve.com",
+			Subject: "Hi\nBcc: eve@eve.com",
+		}, time.Now()))
+
+		assert.NotContains(t, raw, "\r\nBcc:")
+		assert.NotContains(t, raw, "\nBcc:")
+	})
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFileMailer(dir, "no-reply@remember.test")
+
+	require.NoError(t, m.Send(context.Background(), msg))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, ".eml", filepath.Ext(files[0].Name()))
+
+	raw, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "To: bob@bob.com\r\n")
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+
+	require.NoError(t, m.Send(context.Background(), msg))
+
+	assert.Equal(t, []model.Message{*msg}, m.Messages())
+}
+
+// fakeSMTP accepts a single email on a local port and returns what
+// it received
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := l.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) {
+			conn.Write([]byte(s + "\r\n"))
+		}
+
+		var transcript strings.Builder
+
+		reply("220 fake ESMTP")
+
+		for {
+			line, err := r.ReadString('\n')
+
+			if err != nil {
+				return
+			}
+
+			transcript.WriteString(line)
+
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 fake")
+			case cmd == "DATA":
+				reply("354 go ahead")
+
+				for {
+					line, err := r.ReadString('\n')
+
+					if err != nil {
+						return
+					}
+
+					if line == ".\r\n" {
+						break
+					}
+
+					transcript.WriteString(line)
+				}
+
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return l.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	m := NewSMTPMailer(host, port, "", "", "Remember <no-reply@remember.test>")
+
+	require.NoError(t, m.Send(context.Background(), msg))
+
+	select {
+	case transcript := <-received:
+		assert.Contains(t, transcript, "MAIL FROM:<no-reply@remember.test>")
+		assert.Contains(t, transcript, "RCPT TO:<bob@bob.com>")
+		assert.Contains(t, transcript, "Subject: Verify your email\r\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake SMTP server received nothing")
+	}
+}
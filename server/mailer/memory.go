@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+// MemoryMailer keeps sent emails in memory for tests to inspect
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []model.Message
+}
+
+// NewMemoryMailer is a factory for initializing in-memory Mailers
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg *model.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, *msg)
+
+	return nil
+}
+
+// Messages returns the emails sent so far, oldest first
+func (m *MemoryMailer) Messages() []model.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]model.Message(nil), m.messages...)
+}
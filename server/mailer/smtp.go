@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// smtpMailer sends emails through an SMTP server, upgrading to TLS
+// when the server supports STARTTLS
+type smtpMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// NewSMTPMailer is a factory for initializing SMTP Mailers. Without
+// a username no authentication is attempted
+func NewSMTPMailer(host string, port string, username string, password string, from string) model.Mailer {
+	var auth smtp.Auth
+
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpMailer{
+		Addr: net.JoinHostPort(host, port),
+		Auth: auth,
+		From: from,
+	}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg *model.Message) error {
+	from, err := mail.ParseAddress(m.From)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Invalid sender address", "from", m.From, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Invalid recipient address", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := smtp.SendMail(m.Addr, m.Auth, from.Address, []string{to.Address}, format(m.From, msg, time.Now())); err != nil {
+		logging.FromContext(ctx).Error("Unable to send email", "addr", m.Addr, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
@@ -2,49 +2,145 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/vuluu2k/remember_fullstack/server/handler"
+	"github.com/vuluu2k/remember_fullstack/server/config"
+	"github.com/vuluu2k/remember_fullstack/server/health"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/metrics"
 )
 
 func main() {
-	log.Println("Starting server...")
-	router := gin.Default()
+	envTemplate := flag.Bool("env-template", false, "print a .env file documenting every setting and exit")
+	flag.Parse()
 
-	handler.NewHandler(&handler.Config{R: router})
+	if *envTemplate {
+		if err := config.WriteEnvTemplate(os.Stdout); err != nil {
+			fatal("Unable to write env template", err)
+		}
+		return
+	}
+
+	slog.SetDefault(logging.New(os.Stdout, slog.LevelInfo))
+	slog.Info("Starting server...")
+
+	cfg, err := config.Load()
+
+	if err != nil {
+		fatal("Unable to load configuration", err)
+	}
+
+	var level slog.Level
+
+	if err := level.UnmarshalText([]byte(cfg.Server.LogLevel)); err != nil {
+		fatal("Invalid LOG_LEVEL", err)
+	}
+
+	slog.SetDefault(logging.New(os.Stdout, level))
 
+	probe := health.NewProbe(time.Duration(cfg.Server.ReadyTimeoutMillis) * time.Millisecond)
+	api := &apiHandler{}
+
+	// listen right away so probes see the server starting
 	svr := &http.Server{
-		Addr:    ":8080",
-		Handler: router,
+		Addr:    cfg.Server.Addr,
+		Handler: newMux(probe, api),
 	}
 
 	go func() {
 		if err := svr.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Fail to initialize server: %v\n", err)
+			fatal("Fail to initialize server", err)
+		}
+	}()
+
+	slog.Info("Listening", "addr", svr.Addr)
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+
+	metricsSvr := &http.Server{
+		Addr:    cfg.Server.MetricsAddr,
+		Handler: metricsMux,
+	}
+
+	go func() {
+		if err := metricsSvr.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Fail to initialize metrics server", err)
 		}
 	}()
 
-	log.Printf("Listening in http://localhost%v", svr.Addr)
+	slog.Info("Serving metrics", "addr", metricsSvr.Addr)
 
-	quit := make(chan os.Signal)
+	initCtx, cancelInit := context.WithTimeout(context.Background(), time.Duration(cfg.Server.StartupTimeoutSecs)*time.Second)
+	ds, err := initDS(initCtx, cfg)
+	cancelInit()
+
+	if err != nil {
+		fatal("Unable to initialize data sources", err)
+	}
+
+	router, wait, err := inject(ds, cfg)
+
+	if err != nil {
+		if closeErr := ds.close(); closeErr != nil {
+			slog.Error("A problem occurred closing data sources", "err", closeErr)
+		}
+
+		fatal("Failure to inject data sources", err)
+	}
+
+	probe.SetChecks(ds.checks())
+	api.router.Store(router)
+	probe.SetState(health.Ready)
+
+	slog.Info("Ready")
+
+	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	<-quit
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// fail readiness while still serving, giving the proxy time to
+	// stop routing new requests here
+	slog.Info("Draining traffic...", "secs", cfg.Server.DrainSecs)
+	probe.SetState(health.Draining)
+	time.Sleep(time.Duration(cfg.Server.DrainSecs) * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeoutSecs)*time.Second)
 
 	defer cancel()
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server...")
 
 	if err := svr.Shutdown(ctx); err != nil {
-		log.Fatalf("Fail to shutdown server: %v\n", err)
+		fatal("Fail to shutdown server", err)
 	}
+
+	if err := metricsSvr.Shutdown(ctx); err != nil {
+		slog.Error("Fail to shutdown metrics server", "err", err)
+	}
+
+	// emails still being sent need the data sources
+	slog.Info("Waiting for background work...")
+
+	if err := wait(ctx); err != nil {
+		slog.Error("Gave up waiting for background work", "err", err)
+	}
+
+	if err := ds.close(); err != nil {
+		fatal("A problem occurred gracefully shutting down data sources", err)
+	}
+}
+
+// fatal logs err and exits the process
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "remember"
+
+// Sign in failure reasons
+const (
+	SignInUnknownEmail  = "unknown_email"
+	SignInWrongPassword = "wrong_password"
+	SignInWrongCode     = "wrong_two_factor_code"
+	SignInLocked        = "locked"
+	SignInError         = "error"
+)
+
+// Registry holds every metric of the API along with the go runtime
+// and process collectors. It is served by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled by route template, method and status",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// SignUps counts created accounts
+	SignUps = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sign_ups_total",
+		Help:      "Accounts created",
+	})
+
+	// SignInFailures counts rejected sign ins by reason
+	SignInFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sign_in_failures_total",
+		Help:      "Rejected sign ins by reason",
+	}, []string{"reason"})
+
+	// TokenRefreshes counts token pairs issued in exchange for a
+	// refresh token
+	TokenRefreshes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_refreshes_total",
+		Help:      "Token pairs issued in exchange for a refresh token",
+	})
+
+	// RefreshTokenReuses counts refresh tokens presented after they
+	// had already been rotated or revoked
+	RefreshTokenReuses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_token_reuse_detections_total",
+		Help:      "Refresh tokens presented after being rotated or revoked",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requests,
+		requestDuration,
+		SignUps,
+		SignInFailures,
+		TokenRefreshes,
+		RefreshTokenReuses,
+	)
+}
+
+// Handler serves the metrics in Registry. It is meant to be listened
+// on an internal address only
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// knownMethods are recorded by name, any other method as "other" so
+// that clients can't create new series by making methods up
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Middleware records the count and latency of requests by route
+// template, so that path parameters do not create new series.
+// Requests matching no route are recorded as "unmatched", and
+// non-standard methods as "other"
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+
+		if route == "" {
+			route = "unmatched"
+		}
+
+		method := c.Request.Method
+
+		if !knownMethods[method] {
+			method = "other"
+		}
+
+		labels := prometheus.Labels{
+			"route":  route,
+			"method": method,
+			"status": strconv.Itoa(c.Writer.Status()),
+		}
+
+		requests.With(labels).Inc()
+		requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("Records route template", func(t *testing.T) {
+		before := testutil.ToFloat64(requests.WithLabelValues("/users/:id", http.MethodGet, "200"))
+
+		for _, id := range []string{"1", "2"} {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/users/"+id, nil)
+			router.ServeHTTP(rr, request)
+		}
+
+		after := testutil.ToFloat64(requests.WithLabelValues("/users/:id", http.MethodGet, "200"))
+		assert.Equal(t, float64(2), after-before)
+	})
+
+	t.Run("Records unmatched routes under one label", func(t *testing.T) {
+		before := testutil.ToFloat64(requests.WithLabelValues("unmatched", http.MethodGet, "404"))
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/not/a/route", nil)
+		router.ServeHTTP(rr, request)
+
+		after := testutil.ToFloat64(requests.WithLabelValues("unmatched", http.MethodGet, "404"))
+		assert.Equal(t, float64(1), after-before)
+	})
+
+	t.Run("Records made up methods under one label", func(t *testing.T) {
+		before := testutil.ToFloat64(requests.WithLabelValues("unmatched", "other", "404"))
+
+		for _, method := range []string{"FOO", "BAR"} {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(method, "/users/1", nil)
+			router.ServeHTTP(rr, request)
+		}
+
+		after := testutil.ToFloat64(requests.WithLabelValues("unmatched", "other", "404"))
+		assert.Equal(t, float64(2), after-before)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+		Handler().ServeHTTP(rr, request)
+
+		assert.NotContains(t, rr.Body.String(), `method="FOO"`)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	SignUps.Inc()
+
+	rr := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	Handler().ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.Contains(rr.Body.String(), "remember_sign_ups_total"))
+}
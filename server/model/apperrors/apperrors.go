@@ -9,12 +9,14 @@ import (
 type Type string
 
 const (
-	Authorization   Type = "AUTHORIZATION"
-	BadRequest      Type = "BAD_REQUEST"
-	Conflict        Type = "CONFLICT"
-	Internal        Type = "INTERNAL"
-	NotFound        Type = "NOT_FOUND"
-	PayloadTooLarge Type = "PAYLOAD_TOO_LARGE"
+	Authorization      Type = "AUTHORIZATION"
+	BadRequest         Type = "BAD_REQUEST"
+	Conflict           Type = "CONFLICT"
+	Internal           Type = "INTERNAL"
+	NotFound           Type = "NOT_FOUND"
+	PayloadTooLarge    Type = "PAYLOAD_TOO_LARGE"
+	ServiceUnavailable Type = "SERVICE_UNAVAILABLE"
+	TooManyRequests    Type = "TOO_MANY_REQUESTS"
 )
 
 type Error struct {
@@ -40,6 +42,10 @@ func (e *Error) Status() int {
 		return http.StatusNotFound
 	case PayloadTooLarge:
 		return http.StatusRequestEntityTooLarge
+	case ServiceUnavailable:
+		return http.StatusServiceUnavailable
+	case TooManyRequests:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -94,3 +100,17 @@ func NewPayloadTooLarge(maxBodySize int64, contentLength int64) *Error {
 		Message: fmt.Sprintf("Max payload size of %v exceeded. Actual payload size: %v", maxBodySize, contentLength),
 	}
 }
+
+func NewServiceUnavailable() *Error {
+	return &Error{
+		Type:    ServiceUnavailable,
+		Message: "Service unavailable, try again later.",
+	}
+}
+
+func NewTooManyRequests(retryAfterSecs int64) *Error {
+	return &Error{
+		Type:    TooManyRequests,
+		Message: fmt.Sprintf("Too many requests. Try again in %v seconds", retryAfterSecs),
+	}
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity links an account of an external identity provider, named by
+// the provider and its subject claim, to a User
+type Identity struct {
+	Provider string    `db:"provider" json:"provider"`
+	Subject  string    `db:"subject" json:"subject"`
+	UID      uuid.UUID `db:"uid" json:"uid"`
+	// Email is the address the provider gave when the identity was linked
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// OIDCState is kept between sending a user to an identity provider and
+// their return, to check the response belongs to that sign in
+type OIDCState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	// Verifier is the PKCE code verifier the provider expects with the
+	// authorization code
+	Verifier string `json:"verifier"`
+}
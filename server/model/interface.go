@@ -2,6 +2,9 @@ package model
 
 import (
 	"context"
+	"io"
+	"mime/multipart"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,12 +12,155 @@ import (
 type UserService interface {
 	Get(ctx context.Context, uid uuid.UUID) (*User, error)
 	SignUp(ctx context.Context, u *User) error
+	SignIn(ctx context.Context, u *User) error
+	// UpdateDetails reports whether the email changed
+	UpdateDetails(ctx context.Context, u *User) (bool, error)
+	SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*User, error)
+	ClearProfileImage(ctx context.Context, uid uuid.UUID) (*User, error)
+	// ChangePassword sets newPassword if currentPassword is the user's
+	// password and returns the user
+	ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) (*User, error)
 }
 
 type TokenService interface {
 	NewPairFromUser(ctx context.Context, u *User, prevTokenID string) (*TokenPair, error)
+	Signout(ctx context.Context, uid uuid.UUID) error
+	ValidateIDToken(tokenString string) (*User, error)
+	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error)
+}
+
+type VerificationService interface {
+	// SendVerification emails u a link confirming their address,
+	// unless it is verified already
+	SendVerification(ctx context.Context, u *User) error
+	// VerifyEmail consumes a token sent by SendVerification and
+	// returns the verified user
+	VerifyEmail(ctx context.Context, tokenString string) (*User, error)
+}
+
+type PasswordService interface {
+	// ForgotPassword emails the user with email a link to reset their
+	// password in the background. Unknown emails are ignored
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword consumes a token sent by ForgotPassword, sets the
+	// new password and signs the user out everywhere
+	ResetPassword(ctx context.Context, tokenString string, password string) error
+	// Wait blocks until the links ForgotPassword is emailing are sent,
+	// or ctx is done
+	Wait(ctx context.Context) error
+}
+
+type TwoFactorService interface {
+	// Enroll gives the user a new TOTP secret, returned as an
+	// otpauth:// URI for authenticator apps. It is not required at
+	// sign in until Confirm
+	Enroll(ctx context.Context, uid uuid.UUID) (string, error)
+	// Confirm enables two-factor authentication once code matches the
+	// enrolled secret and returns one-time recovery codes
+	Confirm(ctx context.Context, uid uuid.UUID, code string) ([]string, error)
+	// Disable turns two-factor authentication off after checking a code
+	// or recovery code
+	Disable(ctx context.Context, uid uuid.UUID, code string) error
+	// NewChallenge returns a short-lived token for u, who signed in
+	// with their password, to exchange at VerifyChallenge
+	NewChallenge(ctx context.Context, u *User) (string, error)
+	// VerifyChallenge checks a code or recovery code of the user a
+	// challenge token was made for and returns the user
+	VerifyChallenge(ctx context.Context, challengeToken string, code string) (*User, error)
+}
+
+type OIDCService interface {
+	// AuthURL starts signing in with provider, returning the URL to
+	// send the user to and the state their browser must return with
+	AuthURL(ctx context.Context, provider string) (string, string, error)
+	// Callback completes signing in with the code provider returned
+	// with state. The user is found by their identity, else linked or
+	// created by the email the provider verified
+	Callback(ctx context.Context, provider string, state string, code string) (*User, error)
 }
 
 type UserRepository interface {
 	FindById(ctx context.Context, uid uuid.UUID) (*User, error)
+	// Create returns apperrors.Conflict when the email is already taken
+	Create(ctx context.Context, u *User) error
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	// Update returns apperrors.Conflict when the new email is already taken
+	Update(ctx context.Context, u *User) error
+	UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*User, error)
+	// IncrementFailedSignIns returns the number of consecutive failed
+	// sign ins including this one
+	IncrementFailedSignIns(ctx context.Context, uid uuid.UUID) (int, error)
+	LockUntil(ctx context.Context, uid uuid.UUID, until time.Time) error
+	// ResetFailedSignIns clears the failure counter and any lockout
+	ResetFailedSignIns(ctx context.Context, uid uuid.UUID) error
+	// VerifyEmail marks the email of the user verified if it is still
+	// email, returning apperrors.NotFound otherwise
+	VerifyEmail(ctx context.Context, uid uuid.UUID, email string) (*User, error)
+	// UpdatePassword replaces the password hash, which also lifts any
+	// lockout
+	UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error
+	// SetTOTPSecret stores the secret of an enrollment, returning
+	// apperrors.NotFound when two-factor authentication is enabled
+	SetTOTPSecret(ctx context.Context, uid uuid.UUID, secret string) error
+	// EnableTOTP requires the enrolled secret at sign in and replaces
+	// the recovery code hashes
+	EnableTOTP(ctx context.Context, uid uuid.UUID, recoveryCodes []string) error
+	// DisableTOTP clears the secret and recovery codes
+	DisableTOTP(ctx context.Context, uid uuid.UUID) error
+	// UseTOTPStep records the time step of an accepted code, returning
+	// apperrors.Authorization when it isn't later than the last one
+	UseTOTPStep(ctx context.Context, uid uuid.UUID, step int64) error
+	// ConsumeRecoveryCode removes a recovery code hash, returning
+	// apperrors.Authorization when the user doesn't have it
+	ConsumeRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error
+}
+
+type TokenRepository interface {
+	SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
+	// DeleteRefreshToken returns apperrors.Authorization when the token
+	// is not stored, IE. it was already rotated, revoked or has expired
+	DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error
+	DeleteUserRefreshTokens(ctx context.Context, userID string) error
+	SetVerificationToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
+	// DeleteVerificationToken returns apperrors.Authorization when the
+	// token is not stored, IE. it was already used or has expired
+	DeleteVerificationToken(ctx context.Context, userID string, tokenID string) error
+	// SetPasswordResetToken stores the hash of a reset token, replacing
+	// any previous token of the user
+	SetPasswordResetToken(ctx context.Context, userID string, tokenHash string, expiresIn time.Duration) error
+	// ConsumePasswordResetToken deletes a reset token and returns its
+	// user, or apperrors.Authorization when it is not stored
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error)
+	SetOIDCState(ctx context.Context, state string, data *OIDCState, expiresIn time.Duration) error
+	// ConsumeOIDCState returns apperrors.Authorization when state is
+	// unknown, used or expired
+	ConsumeOIDCState(ctx context.Context, state string) (*OIDCState, error)
+}
+
+// IdentityRepository links identity provider accounts to users
+type IdentityRepository interface {
+	FindByProviderSubject(ctx context.Context, provider string, subject string) (*Identity, error)
+	// Create returns apperrors.Conflict when the identity is linked
+	// already
+	Create(ctx context.Context, identity *Identity) error
+}
+
+// ImageRepository stores profile images in some object storage,
+// objName is unique within that storage
+type ImageRepository interface {
+	// UpdateProfile stores image under objName and returns the URL it is served from
+	UpdateProfile(ctx context.Context, objName string, contentType string, image io.Reader) (string, error)
+	DeleteProfile(ctx context.Context, objName string) error
+}
+
+// RateLimitRepository keeps a token bucket per key
+type RateLimitRepository interface {
+	// Take removes a token from the bucket of key. When it is empty no
+	// token is taken and the time until the next refill is returned
+	Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error)
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
 }
@@ -0,0 +1,8 @@
+package model
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+// MockIdentityRepository is a mock type for model.IdentityRepository
+type MockIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdentityRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	ret := m.Called(ctx, provider, subject)
+
+	var r0 *model.Identity
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Identity)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockIdentityRepository) Create(ctx context.Context, identity *model.Identity) error {
+	ret := m.Called(ctx, identity)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+// MockOIDCService is a mock type for model.OIDCService
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) AuthURL(ctx context.Context, provider string) (string, string, error) {
+	ret := m.Called(ctx, provider)
+
+	var r2 error
+
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return ret.String(0), ret.String(1), r2
+}
+
+func (m *MockOIDCService) Callback(ctx context.Context, provider string, state string, code string) (*model.User, error) {
+	ret := m.Called(ctx, provider, state, code)
+
+	var r0 *model.User
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
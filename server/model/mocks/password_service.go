@@ -0,0 +1,48 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasswordService is a mock type for model.PasswordService
+type MockPasswordService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordService) ForgotPassword(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPasswordService) ResetPassword(ctx context.Context, tokenString string, password string) error {
+	ret := m.Called(ctx, tokenString, password)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPasswordService) Wait(ctx context.Context) error {
+	ret := m.Called(ctx)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
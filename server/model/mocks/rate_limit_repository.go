@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+type MockRateLimitRepository struct {
+	mock.Mock
+}
+
+func (m *MockRateLimitRepository) Take(ctx context.Context, key string, limit model.RateLimit) (time.Duration, error) {
+	ret := m.Called(ctx, key, limit)
+
+	var r0 time.Duration
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
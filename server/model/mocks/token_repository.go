@@ -0,0 +1,127 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, tokenID, expiresIn)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error {
+	ret := m.Called(ctx, userID, prevTokenID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	ret := m.Called(ctx, userID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) SetVerificationToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, tokenID, expiresIn)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) DeleteVerificationToken(ctx context.Context, userID string, tokenID string) error {
+	ret := m.Called(ctx, userID, tokenID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) SetPasswordResetToken(ctx context.Context, userID string, tokenHash string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, tokenHash, expiresIn)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.String(0), r1
+}
+
+func (m *MockTokenRepository) SetOIDCState(ctx context.Context, state string, data *model.OIDCState, expiresIn time.Duration) error {
+	ret := m.Called(ctx, state, data, expiresIn)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) ConsumeOIDCState(ctx context.Context, state string) (*model.OIDCState, error) {
+	ret := m.Called(ctx, state)
+
+	var r0 *model.OIDCState
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCState)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
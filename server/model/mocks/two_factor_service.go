@@ -0,0 +1,86 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+// MockTwoFactorService is a mock type for model.TwoFactorService
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) Enroll(ctx context.Context, uid uuid.UUID) (string, error) {
+	ret := m.Called(ctx, uid)
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.String(0), r1
+}
+
+func (m *MockTwoFactorService) Confirm(ctx context.Context, uid uuid.UUID, code string) ([]string, error) {
+	ret := m.Called(ctx, uid, code)
+
+	var r0 []string
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTwoFactorService) Disable(ctx context.Context, uid uuid.UUID, code string) error {
+	ret := m.Called(ctx, uid, code)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTwoFactorService) NewChallenge(ctx context.Context, u *model.User) (string, error) {
+	ret := m.Called(ctx, u)
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.String(0), r1
+}
+
+func (m *MockTwoFactorService) VerifyChallenge(ctx context.Context, challengeToken string, code string) (*model.User, error) {
+	ret := m.Called(ctx, challengeToken, code)
+
+	var r0 *model.User
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"mime/multipart"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -46,3 +47,87 @@ func (m *MockUserService) SignUp(ctx context.Context, u *model.User) error {
 
 	return r0
 }
+
+func (m *MockUserService) SignIn(ctx context.Context, u *model.User) error {
+	ret := m.Called(ctx, u)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*model.User, error) {
+	ret := m.Called(ctx, uid, imageFileHeader)
+
+	var r0 *model.User
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) ClearProfileImage(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.User
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) UpdateDetails(ctx context.Context, u *model.User) (bool, error) {
+	ret := m.Called(ctx, u)
+
+	var r0 bool
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) (*model.User, error) {
+	ret := m.Called(ctx, uid, currentPassword, newPassword)
+
+	var r0 *model.User
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
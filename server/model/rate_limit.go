@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// RateLimit describes a token bucket holding up to Burst tokens,
+// refilled with one token every Interval
+type RateLimit struct {
+	Burst    int64
+	Interval time.Duration
+}
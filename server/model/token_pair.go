@@ -1,6 +1,16 @@
 package model
 
+import "github.com/google/uuid"
+
 type TokenPair struct {
 	TokenID      string `json:"token_id"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+// RefreshToken stores token properties that
+// are accessed in multiple application layers
+type RefreshToken struct {
+	ID  uuid.UUID `json:"-"`
+	UID uuid.UUID `json:"-"`
+	SS  string    `json:"refreshToken"`
+}
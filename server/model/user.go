@@ -1,12 +1,63 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
 
 type User struct {
 	UID      uuid.UUID `db:"uid" json:"uid"`
 	Email    string    `db:"email" json:"email"`
-	Password string    `db:"password" json:"password"`
+	Password string    `db:"password" json:"-"`
 	Name     string    `db:"name" json:"name"`
 	ImageUrl string    `db:"image_url" json:"image_url"`
 	Website  string    `db:"website" json:"website"`
+	// EmailVerified is reset whenever the email changes
+	EmailVerified bool `db:"email_verified" json:"email_verified"`
+	// FailedSignIns counts wrong passwords since the last sign in
+	FailedSignIns int `db:"failed_sign_ins" json:"-"`
+	// LockedUntil is set while sign ins are refused after too many
+	// failed ones
+	LockedUntil *time.Time `db:"locked_until" json:"-"`
+	// TOTPSecret is the base32 secret of an enrolled authenticator,
+	// TOTPEnabled is only set once a code confirmed it
+	TOTPSecret  string `db:"totp_secret" json:"-"`
+	TOTPEnabled bool   `db:"totp_enabled" json:"totp_enabled"`
+	// TOTPLastStep is the time step of the last code accepted, so
+	// codes can't be replayed
+	TOTPLastStep int64 `db:"totp_last_step" json:"-"`
+	// RecoveryCodes holds hashes of the unused recovery codes
+	RecoveryCodes pq.StringArray `db:"recovery_codes" json:"-"`
+}
+
+// IsLocked reports whether sign ins are refused at now
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// PublicUser is the representation of a User handed to clients
+// Handlers must respond with it rather than with a User
+type PublicUser struct {
+	UID           uuid.UUID `json:"uid"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	Name          string    `json:"name"`
+	ImageUrl      string    `json:"image_url"`
+	Website       string    `json:"website"`
+}
+
+// Public returns the fields of u that are safe to send to clients
+func (u *User) Public() *PublicUser {
+	return &PublicUser{
+		UID:           u.UID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		TOTPEnabled:   u.TOTPEnabled,
+		Name:          u.Name,
+		ImageUrl:      u.ImageUrl,
+		Website:       u.Website,
+	}
 }
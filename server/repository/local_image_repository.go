@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// localImageRepository is an ImageRepository keeping images in a
+// directory on the local filesystem, for development and single
+// instance deployments. The directory is expected to be served under
+// BaseURL
+type localImageRepository struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewImageRepository is a factory for initializing local filesystem Image Repositories
+func NewImageRepository(dir string, baseURL string) model.ImageRepository {
+	return &localImageRepository{
+		Dir:     dir,
+		BaseURL: baseURL,
+	}
+}
+
+func (r *localImageRepository) UpdateProfile(ctx context.Context, objName string, contentType string, image io.Reader) (string, error) {
+	objPath, err := r.objectPath(objName)
+
+	if err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first so readers never see a partial image
+	tmp, err := os.CreateTemp(r.Dir, ".upload-*")
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to create temporary image file", "dir", r.Dir, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, image); err != nil {
+		tmp.Close()
+		logging.FromContext(ctx).Error("Unable to write image", "object", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := tmp.Close(); err != nil {
+		logging.FromContext(ctx).Error("Unable to write image", "object", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		logging.FromContext(ctx).Error("Unable to set permissions of image", "object", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := os.Rename(tmp.Name(), objPath); err != nil {
+		logging.FromContext(ctx).Error("Unable to move image into place", "object", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return r.BaseURL + "/" + path.Clean(objName), nil
+}
+
+func (r *localImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	objPath, err := r.objectPath(objName)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(objPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logging.FromContext(ctx).Error("Failed to delete image object", "object", objName, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// objectPath resolves objName inside Dir, refusing names that would escape it
+func (r *localImageRepository) objectPath(objName string) (string, error) {
+	if err := checkImageName(objName); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(r.Dir, objName), nil
+}
+
+// checkImageName refuses object names that aren't a single path element
+func checkImageName(objName string) error {
+	if objName == "" || objName != filepath.Base(objName) || objName == "." || objName == ".." {
+		return apperrors.NewBadRequest(fmt.Sprintf("invalid image name: %v", objName))
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalImageRepository(t *testing.T) {
+	dir := t.TempDir()
+	r := NewImageRepository(dir, "/images")
+	ctx := context.Background()
+
+	t.Run("Update and delete profile image", func(t *testing.T) {
+		url, err := r.UpdateProfile(ctx, "avatar.png", "image/png", strings.NewReader("image bytes"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/images/avatar.png", url)
+
+		contents, err := os.ReadFile(filepath.Join(dir, "avatar.png"))
+		assert.NoError(t, err)
+		assert.Equal(t, "image bytes", string(contents))
+
+		assert.NoError(t, r.DeleteProfile(ctx, "avatar.png"))
+
+		_, err = os.Stat(filepath.Join(dir, "avatar.png"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Delete missing image", func(t *testing.T) {
+		assert.NoError(t, r.DeleteProfile(ctx, "missing.png"))
+	})
+
+	t.Run("Rejects names outside the directory", func(t *testing.T) {
+		_, err := r.UpdateProfile(ctx, "../escape.png", "image/png", strings.NewReader("image bytes"))
+		assert.Error(t, err)
+
+		assert.Error(t, r.DeleteProfile(ctx, "../escape.png"))
+	})
+}
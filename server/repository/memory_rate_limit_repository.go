@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket has refilled completely under its own limit
+	full time.Time
+}
+
+// memoryRateLimitRepository keeps token buckets in process memory,
+// for tests and single instance development setups. Buckets are
+// forgotten once they would be full again
+type memoryRateLimitRepository struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryRateLimitRepository is a factory for initializing in-memory Rate Limit Repositories
+func NewMemoryRateLimitRepository() model.RateLimitRepository {
+	return &memoryRateLimitRepository{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (r *memoryRateLimitRepository) Take(ctx context.Context, key string, limit model.RateLimit) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.prune(now)
+
+	b, ok := r.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		r.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, now.Sub(b.updated), limit)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) * float64(limit.Interval)), nil
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) * float64(limit.Interval)))
+
+	return 0, nil
+}
+
+// prune drops buckets which have refilled completely, as they are
+// indistinguishable from new ones. Each bucket is judged by the limit
+// it was last taken from, keys of other limits may refill slower
+func (r *memoryRateLimitRepository) prune(now time.Time) {
+	for key, b := range r.buckets {
+		if !now.Before(b.full) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+// refill adds the tokens accrued over elapsed, up to the burst
+func refill(tokens float64, elapsed time.Duration, limit model.RateLimit) float64 {
+	tokens += float64(elapsed) / float64(limit.Interval)
+
+	if max := float64(limit.Burst); tokens > max {
+		return max
+	}
+
+	return tokens
+}
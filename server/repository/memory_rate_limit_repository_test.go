@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+func TestMemoryRateLimitRepository(t *testing.T) {
+	ctx := context.Background()
+	limit := model.RateLimit{Burst: 2, Interval: 10 * time.Second}
+
+	now := time.Now()
+	r := NewMemoryRateLimitRepository().(*memoryRateLimitRepository)
+	r.now = func() time.Time { return now }
+
+	t.Run("Allows burst then waits for refill", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			wait, err := r.Take(ctx, "a", limit)
+			assert.NoError(t, err)
+			assert.Zero(t, wait)
+		}
+
+		wait, err := r.Take(ctx, "a", limit)
+		assert.NoError(t, err)
+		assert.Equal(t, 10*time.Second, wait)
+
+		now = now.Add(4 * time.Second)
+
+		wait, err = r.Take(ctx, "a", limit)
+		assert.NoError(t, err)
+		assert.Equal(t, 6*time.Second, wait)
+
+		now = now.Add(6 * time.Second)
+
+		wait, err = r.Take(ctx, "a", limit)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("Keys have separate buckets", func(t *testing.T) {
+		wait, err := r.Take(ctx, "b", limit)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("Forgets full buckets", func(t *testing.T) {
+		now = now.Add(time.Minute)
+
+		_, err := r.Take(ctx, "c", limit)
+		assert.NoError(t, err)
+
+		assert.Len(t, r.buckets, 1)
+	})
+
+	t.Run("Keeps buckets of slower limits", func(t *testing.T) {
+		slow := model.RateLimit{Burst: 1, Interval: time.Hour}
+
+		_, err := r.Take(ctx, "slow", slow)
+		assert.NoError(t, err)
+
+		// long enough to refill any bucket of limit, not of slow
+		now = now.Add(time.Minute)
+
+		_, err = r.Take(ctx, "fast", limit)
+		assert.NoError(t, err)
+
+		wait, err := r.Take(ctx, "slow", slow)
+		assert.NoError(t, err)
+		assert.Equal(t, 59*time.Minute, wait)
+	})
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+)
+
+// migrationFS holds the versioned schema migrations. Files are named
+// "<version>_<description>.sql" and are applied in version order
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationLockID is the postgres advisory lock key held while migrating
+// so that several instances starting at once don't race each other
+const migrationLockID = 72710402
+
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrate applies every embedded migration that has not yet been
+// recorded in the schema_migrations table. Each migration runs
+// in its own transaction
+func Migrate(ctx context.Context, db *sqlx.DB) error {
+	migrations, err := loadMigrations(migrationFS)
+
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Connx(ctx)
+
+	if err != nil {
+		return fmt.Errorf("unable to acquire migration connection: %w", err)
+	}
+
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("unable to acquire migration lock: %w", err)
+	}
+
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	createTable := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("unable to create schema_migrations table: %w", err)
+	}
+
+	var current int
+
+	if err := conn.GetContext(ctx, &current, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"); err != nil {
+		return fmt.Errorf("unable to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		logging.FromContext(ctx).Info("Applying migration", "version", m.Version, "name", m.Name)
+
+		tx, err := conn.BeginTxx(ctx, nil)
+
+		if err != nil {
+			return fmt.Errorf("unable to begin migration %d: %w", m.Version, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %04d_%v failed: %w", m.Version, m.Name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("unable to record migration %d: %w", m.Version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("unable to commit migration %d: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// loadMigrations reads and sorts the migrations found in fsys
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+
+		if !ok {
+			return nil, fmt.Errorf("migration %v must be named <version>_<description>.sql", file)
+		}
+
+		version, err := strconv.Atoi(versionStr)
+
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %v has an invalid version", file)
+		}
+
+		if prev, exists := seen[version]; exists {
+			return nil, fmt.Errorf("migrations %v and %v share version %d", prev, file, version)
+		}
+
+		seen[version] = file
+
+		contents, err := fs.ReadFile(fsys, file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			Version: version,
+			Name:    name,
+			SQL:     string(contents),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
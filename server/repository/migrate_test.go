@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("Embedded", func(t *testing.T) {
+		migrations, err := loadMigrations(migrationFS)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+		assert.Equal(t, 1, migrations[0].Version)
+		assert.Equal(t, "create_users", migrations[0].Name)
+	})
+
+	t.Run("Sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0010_third.sql":  {Data: []byte("SELECT 3;")},
+			"migrations/0002_second.sql": {Data: []byte("SELECT 2;")},
+			"migrations/0001_first.sql":  {Data: []byte("SELECT 1;")},
+		}
+
+		migrations, err := loadMigrations(fsys)
+
+		assert.NoError(t, err)
+		assert.Len(t, migrations, 3)
+		assert.Equal(t, []int{1, 2, 10}, []int{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+		assert.Equal(t, "SELECT 3;", migrations[2].SQL)
+	})
+
+	t.Run("Duplicate version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_first.sql":   {Data: []byte("SELECT 1;")},
+			"migrations/0001_another.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		_, err := loadMigrations(fsys)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid name", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/first.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		_, err := loadMigrations(fsys)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users (
+    uid UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email VARCHAR NOT NULL UNIQUE,
+    password VARCHAR NOT NULL,
+    name VARCHAR NOT NULL DEFAULT '',
+    image_url VARCHAR NOT NULL DEFAULT '',
+    website VARCHAR NOT NULL DEFAULT ''
+);
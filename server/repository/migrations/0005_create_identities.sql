@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS identities (
+    provider VARCHAR NOT NULL,
+    subject VARCHAR NOT NULL,
+    uid UUID NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    email VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS identities_uid_idx ON identities (uid);
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// pGIdentityRepository is data/repository implementation
+// of service layer IdentityRepository
+type pGIdentityRepository struct {
+	DB *sqlx.DB
+}
+
+// NewIdentityRepository is a factory for initializing Identity Repositories
+func NewIdentityRepository(db *sqlx.DB) model.IdentityRepository {
+	return &pGIdentityRepository{
+		DB: db,
+	}
+}
+
+func (r *pGIdentityRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	identity := &model.Identity{}
+
+	query := "SELECT * FROM identities WHERE provider=$1 AND subject=$2"
+
+	if err := r.DB.GetContext(ctx, identity, query, provider, subject); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("identity", provider+":"+subject)
+		}
+
+		logging.FromContext(ctx).Error("Unable to get identity", "provider", provider, "subject", subject, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return identity, nil
+}
+
+func (r *pGIdentityRepository) Create(ctx context.Context, identity *model.Identity) error {
+	query := "INSERT INTO identities (provider, subject, uid, email) VALUES ($1, $2, $3, $4) RETURNING *"
+
+	if err := r.DB.GetContext(ctx, identity, query, identity.Provider, identity.Subject, identity.UID, identity.Email); err != nil {
+		if isUniqueViolation(err) {
+			logging.FromContext(ctx).Info("Could not link identity, it is linked already", "provider", identity.Provider, "subject", identity.Subject)
+			return apperrors.NewConflict("identity", identity.Provider+":"+identity.Subject)
+		}
+
+		logging.FromContext(ctx).Error("Could not link identity", "provider", identity.Provider, "uid", identity.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+func TestPGIdentityRepository(t *testing.T) {
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+	r := NewIdentityRepository(db)
+	ctx := context.Background()
+
+	u := &model.User{
+		Email:         uuid.NewString() + "@remember.test",
+		EmailVerified: true,
+	}
+
+	require.NoError(t, users.Create(ctx, u))
+	assert.True(t, u.EmailVerified)
+
+	subject := uuid.NewString()
+
+	t.Run("Create and find", func(t *testing.T) {
+		identity := &model.Identity{
+			Provider: "oidc",
+			Subject:  subject,
+			UID:      u.UID,
+			Email:    u.Email,
+		}
+
+		require.NoError(t, r.Create(ctx, identity))
+		assert.False(t, identity.CreatedAt.IsZero())
+
+		found, err := r.FindByProviderSubject(ctx, "oidc", subject)
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, found.UID)
+		assert.Equal(t, u.Email, found.Email)
+	})
+
+	t.Run("Subject linked already", func(t *testing.T) {
+		err := r.Create(ctx, &model.Identity{Provider: "oidc", Subject: subject, UID: u.UID})
+
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := r.FindByProviderSubject(ctx, "other", subject)
+
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+}
@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// pGUserRepository is data/repository implementation
+// of service layer UserRepository
+type pGUserRepository struct {
+	DB *sqlx.DB
+}
+
+// NewUserRepository is a factory for initializing User Repositories
+func NewUserRepository(db *sqlx.DB) model.UserRepository {
+	return &pGUserRepository{
+		DB: db,
+	}
+}
+
+func (r *pGUserRepository) Create(ctx context.Context, u *model.User) error {
+	query := "INSERT INTO users (email, password, email_verified) VALUES ($1, $2, $3) RETURNING *"
+
+	if err := r.DB.GetContext(ctx, u, query, u.Email, u.Password, u.EmailVerified); err != nil {
+		if isUniqueViolation(err) {
+			logging.FromContext(ctx).Info("Could not create a user, email already registered", "email", u.Email)
+			return apperrors.NewConflict("email", u.Email)
+		}
+
+		logging.FromContext(ctx).Error("Could not create a user", "email", u.Email, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pGUserRepository) FindById(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	user := &model.User{}
+
+	query := "SELECT * FROM users WHERE uid=$1"
+
+	if err := r.DB.GetContext(ctx, user, query, uid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("uid", uid.String())
+		}
+
+		logging.FromContext(ctx).Error("Unable to get user", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+func (r *pGUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	user := &model.User{}
+
+	query := "SELECT * FROM users WHERE email=$1"
+
+	if err := r.DB.GetContext(ctx, user, query, email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("email", email)
+		}
+
+		logging.FromContext(ctx).Error("Unable to get user by email", "email", email, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+func (r *pGUserRepository) Update(ctx context.Context, u *model.User) error {
+	query := `
+		UPDATE users
+		SET name=:name, email=:email, website=:website,
+			email_verified=(email_verified AND email=:email)
+		WHERE uid=:uid
+		RETURNING *;
+	`
+
+	nstmt, err := r.DB.PrepareNamedContext(ctx, query)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to prepare user update query", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	defer nstmt.Close()
+
+	if err := nstmt.GetContext(ctx, u, u); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperrors.NewNotFound("uid", u.UID.String())
+		}
+
+		if isUniqueViolation(err) {
+			logging.FromContext(ctx).Info("Could not update user email", "uid", u.UID, "email", u.Email, "err", err)
+			return apperrors.NewConflict("email", u.Email)
+		}
+
+		logging.FromContext(ctx).Error("Failed to update details for user", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateImage updates a user's image separately from
+// other account details
+func (r *pGUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET image_url=$2
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	u := &model.User{}
+
+	if err := r.DB.GetContext(ctx, u, query, uid, imageURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("uid", uid.String())
+		}
+
+		logging.FromContext(ctx).Error("Error updating image_url in database", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+// IncrementFailedSignIns counts a failed sign in atomically, so
+// concurrent attempts can't lose failures
+func (r *pGUserRepository) IncrementFailedSignIns(ctx context.Context, uid uuid.UUID) (int, error) {
+	query := `
+		UPDATE users
+		SET failed_sign_ins=failed_sign_ins + 1
+		WHERE uid=$1
+		RETURNING failed_sign_ins;
+	`
+
+	var failures int
+
+	if err := r.DB.GetContext(ctx, &failures, query, uid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, apperrors.NewNotFound("uid", uid.String())
+		}
+
+		logging.FromContext(ctx).Error("Unable to count failed sign in", "uid", uid, "err", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return failures, nil
+}
+
+func (r *pGUserRepository) LockUntil(ctx context.Context, uid uuid.UUID, until time.Time) error {
+	query := "UPDATE users SET locked_until=$2 WHERE uid=$1"
+
+	if _, err := r.DB.ExecContext(ctx, query, uid, until); err != nil {
+		logging.FromContext(ctx).Error("Unable to lock user", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pGUserRepository) ResetFailedSignIns(ctx context.Context, uid uuid.UUID) error {
+	query := "UPDATE users SET failed_sign_ins=0, locked_until=NULL WHERE uid=$1"
+
+	if _, err := r.DB.ExecContext(ctx, query, uid); err != nil {
+		logging.FromContext(ctx).Error("Unable to reset failed sign ins", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// VerifyEmail only verifies email if the user hasn't changed it since
+func (r *pGUserRepository) VerifyEmail(ctx context.Context, uid uuid.UUID, email string) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET email_verified=TRUE
+		WHERE uid=$1 AND email=$2
+		RETURNING *;
+	`
+
+	u := &model.User{}
+
+	if err := r.DB.GetContext(ctx, u, query, uid, email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("email", email)
+		}
+
+		logging.FromContext(ctx).Error("Unable to verify email", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+func (r *pGUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	query := `
+		UPDATE users
+		SET password=$2, failed_sign_ins=0, locked_until=NULL
+		WHERE uid=$1;
+	`
+
+	res, err := r.DB.ExecContext(ctx, query, uid, password)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to update password", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// SetTOTPSecret won't replace the secret of an enabled authenticator,
+// which would lock its user out
+func (r *pGUserRepository) SetTOTPSecret(ctx context.Context, uid uuid.UUID, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret=$2, totp_last_step=0
+		WHERE uid=$1 AND NOT totp_enabled;
+	`
+
+	res, err := r.DB.ExecContext(ctx, query, uid, secret)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to set TOTP secret", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+func (r *pGUserRepository) EnableTOTP(ctx context.Context, uid uuid.UUID, recoveryCodes []string) error {
+	query := `
+		UPDATE users
+		SET totp_enabled=TRUE, recovery_codes=$2
+		WHERE uid=$1 AND totp_secret<>'';
+	`
+
+	res, err := r.DB.ExecContext(ctx, query, uid, pq.StringArray(recoveryCodes))
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to enable TOTP", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+func (r *pGUserRepository) DisableTOTP(ctx context.Context, uid uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET totp_secret='', totp_enabled=FALSE, totp_last_step=0, recovery_codes='{}'
+		WHERE uid=$1;
+	`
+
+	if _, err := r.DB.ExecContext(ctx, query, uid); err != nil {
+		logging.FromContext(ctx).Error("Unable to disable TOTP", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UseTOTPStep only moves forward, so concurrent requests can't both
+// use the same code
+func (r *pGUserRepository) UseTOTPStep(ctx context.Context, uid uuid.UUID, step int64) error {
+	query := `
+		UPDATE users
+		SET totp_last_step=$2
+		WHERE uid=$1 AND totp_last_step < $2;
+	`
+
+	res, err := r.DB.ExecContext(ctx, query, uid, step)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to use TOTP step", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		logging.FromContext(ctx).Info("TOTP code was already used", "uid", uid, "step", step)
+		return apperrors.NewAuthorization("Two-factor code has already been used")
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode removes the code in the statement checking it,
+// so each can only be used once
+func (r *pGUserRepository) ConsumeRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error {
+	query := `
+		UPDATE users
+		SET recovery_codes=array_remove(recovery_codes, $2)
+		WHERE uid=$1 AND $2=ANY(recovery_codes);
+	`
+
+	res, err := r.DB.ExecContext(ctx, query, uid, codeHash)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to consume recovery code", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		logging.FromContext(ctx).Info("Recovery code does not exist", "uid", uid)
+		return apperrors.NewAuthorization("Invalid recovery code")
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation"
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// takeScript refills and takes from a token bucket atomically, using
+// the clock of redis so every API instance agrees on the time. It
+// returns 0 when a token was taken, otherwise the milliseconds until
+// the next one is available
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - updated) / interval)
+
+local wait = 0
+
+if tokens < 1 then
+	wait = math.ceil((1 - tokens) * interval)
+else
+	tokens = tokens - 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], burst * interval)
+
+return wait
+`)
+
+// redisRateLimitRepository is a RateLimitRepository shared by every
+// instance of the API
+type redisRateLimitRepository struct {
+	Redis *redis.Client
+}
+
+// NewRateLimitRepository is a factory for initializing Redis Rate Limit Repositories
+func NewRateLimitRepository(redisClient *redis.Client) model.RateLimitRepository {
+	return &redisRateLimitRepository{
+		Redis: redisClient,
+	}
+}
+
+// rateLimitKey is kept apart from the uid prefixed refresh token keys
+func rateLimitKey(key string) string {
+	return "ratelimit:" + key
+}
+
+func (r *redisRateLimitRepository) Take(ctx context.Context, key string, limit model.RateLimit) (time.Duration, error) {
+	wait, err := takeScript.Run(ctx, r.Redis, []string{rateLimitKey(key)}, limit.Burst, limit.Interval.Milliseconds()).Int64()
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not take rate limit token from redis", "key", key, "err", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
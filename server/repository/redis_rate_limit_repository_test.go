@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+func TestRedisRateLimitRepository(t *testing.T) {
+	rdb := openTestRedis(t)
+	r := NewRateLimitRepository(rdb)
+	ctx := context.Background()
+
+	limit := model.RateLimit{Burst: 2, Interval: time.Minute}
+	key := "test:" + uuid.NewString()
+
+	t.Cleanup(func() {
+		rdb.Del(ctx, rateLimitKey(key))
+	})
+
+	for i := 0; i < 2; i++ {
+		wait, err := r.Take(ctx, key, limit)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	wait, err := r.Take(ctx, key, limit)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, wait, float64(time.Second))
+
+	ttl, err := rdb.PTTL(ctx, rateLimitKey(key)).Result()
+	assert.NoError(t, err)
+	assert.InDelta(t, 2*time.Minute, ttl, float64(5*time.Second))
+}
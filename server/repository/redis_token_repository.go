@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// redisTokenRepository is data/repository implementation
+// of service layer TokenRepository
+type redisTokenRepository struct {
+	Redis *redis.Client
+}
+
+// NewTokenRepository is a factory for initializing Token Repositories
+func NewTokenRepository(redisClient *redis.Client) model.TokenRepository {
+	return &redisTokenRepository{
+		Redis: redisClient,
+	}
+}
+
+// refreshTokenKey is the redis key of a single refresh token,
+// prefixed by the user so all of a user's tokens can be found
+func refreshTokenKey(userID string, tokenID string) string {
+	return fmt.Sprintf("%s:%s", userID, tokenID)
+}
+
+// verificationTokenKey is kept apart from the uid prefixed refresh
+// token keys, so signing out doesn't void pending verifications
+func verificationTokenKey(userID string, tokenID string) string {
+	return fmt.Sprintf("verify:%s:%s", userID, tokenID)
+}
+
+// passwordResetKey maps the hash of a reset token to its user, and
+// passwordResetUserKey the user to the hash of their current token
+func passwordResetKey(tokenHash string) string {
+	return fmt.Sprintf("reset:%s", tokenHash)
+}
+
+func passwordResetUserKey(userID string) string {
+	return fmt.Sprintf("reset-user:%s", userID)
+}
+
+func oidcStateKey(state string) string {
+	return fmt.Sprintf("oidc:%s", state)
+}
+
+// SetRefreshToken stores a refresh token with an expiry time
+func (r *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
+	// We'll store userID with token id so we can scan (non-blocking)
+	// over the user's tokens and delete them in case of token leakage
+	key := refreshTokenKey(userID, tokenID)
+
+	if err := r.Redis.Set(ctx, key, 0, expiresIn).Err(); err != nil {
+		logging.FromContext(ctx).Error("Could not SET refresh token to redis", "uid", userID, "token_id", tokenID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// DeleteRefreshToken used to delete old refresh tokens
+// Services may access this to rotate tokens
+func (r *redisTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, tokenID string) error {
+	key := refreshTokenKey(userID, tokenID)
+
+	result := r.Redis.Del(ctx, key)
+
+	if err := result.Err(); err != nil {
+		logging.FromContext(ctx).Error("Could not delete refresh token from redis", "uid", userID, "token_id", tokenID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	// Val returns count of deleted keys.
+	// If no key was deleted, the refresh token is invalid
+	if result.Val() < 1 {
+		logging.FromContext(ctx).Info("Refresh token does not exist in redis", "uid", userID, "token_id", tokenID)
+		return apperrors.NewAuthorization("Invalid refresh token")
+	}
+
+	return nil
+}
+
+// DeleteUserRefreshTokens looks for all tokens beginning with
+// userID and scans to delete them in a non-blocking fashion
+func (r *redisTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	pattern := refreshTokenKey(userID, "*")
+
+	iter := r.Redis.Scan(ctx, 0, pattern, 5).Iterator()
+	failCount := 0
+
+	for iter.Next(ctx) {
+		if err := r.Redis.Del(ctx, iter.Val()).Err(); err != nil {
+			logging.FromContext(ctx).Error("Failed to delete refresh token", "uid", userID, "err", err)
+			failCount++
+		}
+	}
+
+	// check last value
+	if err := iter.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to scan refresh tokens", "uid", userID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if failCount > 0 {
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// SetVerificationToken stores an email verification token until it is
+// used or expires
+func (r *redisTokenRepository) SetVerificationToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
+	key := verificationTokenKey(userID, tokenID)
+
+	if err := r.Redis.Set(ctx, key, 0, expiresIn).Err(); err != nil {
+		logging.FromContext(ctx).Error("Could not SET verification token to redis", "uid", userID, "token_id", tokenID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// DeleteVerificationToken consumes a verification token, so each can
+// only be used once
+func (r *redisTokenRepository) DeleteVerificationToken(ctx context.Context, userID string, tokenID string) error {
+	key := verificationTokenKey(userID, tokenID)
+
+	result := r.Redis.Del(ctx, key)
+
+	if err := result.Err(); err != nil {
+		logging.FromContext(ctx).Error("Could not delete verification token from redis", "uid", userID, "token_id", tokenID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if result.Val() < 1 {
+		logging.FromContext(ctx).Info("Verification token does not exist in redis", "uid", userID, "token_id", tokenID)
+		return apperrors.NewAuthorization("Invalid verification token")
+	}
+
+	return nil
+}
+
+// SetPasswordResetToken stores a reset token hash until it is used or
+// expires. A user only ever has one, so older links stop working
+func (r *redisTokenRepository) SetPasswordResetToken(ctx context.Context, userID string, tokenHash string, expiresIn time.Duration) error {
+	prev, err := r.Redis.GetDel(ctx, passwordResetUserKey(userID)).Result()
+
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx).Error("Could not get previous password reset token from redis", "uid", userID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	_, err = r.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if prev != "" {
+			pipe.Del(ctx, passwordResetKey(prev))
+		}
+
+		pipe.Set(ctx, passwordResetKey(tokenHash), userID, expiresIn)
+		pipe.Set(ctx, passwordResetUserKey(userID), tokenHash, expiresIn)
+
+		return nil
+	})
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not SET password reset token to redis", "uid", userID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumePasswordResetToken atomically takes a reset token, so each
+// can only be used once
+func (r *redisTokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	userID, err := r.Redis.GetDel(ctx, passwordResetKey(tokenHash)).Result()
+
+	if errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx).Info("Password reset token does not exist in redis")
+		return "", apperrors.NewAuthorization("Invalid password reset token")
+	}
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not get password reset token from redis", "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := r.Redis.Del(ctx, passwordResetUserKey(userID)).Err(); err != nil {
+		logging.FromContext(ctx).Warn("Could not delete password reset token of user from redis", "uid", userID, "err", err)
+	}
+
+	return userID, nil
+}
+
+// SetOIDCState keeps what is needed to complete an identity provider
+// sign in until the user returns or it expires
+func (r *redisTokenRepository) SetOIDCState(ctx context.Context, state string, data *model.OIDCState, expiresIn time.Duration) error {
+	value, err := json.Marshal(data)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not encode OIDC state", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := r.Redis.Set(ctx, oidcStateKey(state), value, expiresIn).Err(); err != nil {
+		logging.FromContext(ctx).Error("Could not SET OIDC state to redis", "provider", data.Provider, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumeOIDCState atomically takes an OIDC state, so each sign in can
+// only be completed once
+func (r *redisTokenRepository) ConsumeOIDCState(ctx context.Context, state string) (*model.OIDCState, error) {
+	value, err := r.Redis.GetDel(ctx, oidcStateKey(state)).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx).Info("OIDC state does not exist in redis")
+		return nil, apperrors.NewAuthorization("Invalid sign in state")
+	}
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Could not get OIDC state from redis", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	data := &model.OIDCState{}
+
+	if err := json.Unmarshal(value, data); err != nil {
+		logging.FromContext(ctx).Error("Could not decode OIDC state", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return data, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// openTestRedis connects to the redis given by REDIS_TEST_ADDR, eg. the
+// redis-remember service from docker-compose:
+//
+//	REDIS_TEST_ADDR="localhost:6379" go test ./repository/...
+func openTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_TEST_ADDR")
+
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR not set, skipping redis tests")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+
+	require.NoError(t, rdb.Ping(context.Background()).Err())
+
+	t.Cleanup(func() {
+		rdb.Close()
+	})
+
+	return rdb
+}
+
+func TestRedisTokenRepository(t *testing.T) {
+	rdb := openTestRedis(t)
+	r := NewTokenRepository(rdb)
+	ctx := context.Background()
+
+	userID := uuid.NewString()
+
+	t.Run("Set and delete refresh token", func(t *testing.T) {
+		tokenID := uuid.NewString()
+
+		assert.NoError(t, r.SetRefreshToken(ctx, userID, tokenID, time.Minute))
+
+		ttl, err := rdb.TTL(ctx, refreshTokenKey(userID, tokenID)).Result()
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Minute, ttl, float64(5*time.Second))
+
+		assert.NoError(t, r.DeleteRefreshToken(ctx, userID, tokenID))
+
+		// deleting a second time means the token was already rotated
+		err = r.DeleteRefreshToken(ctx, userID, tokenID)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Delete all user refresh tokens", func(t *testing.T) {
+		otherUserID := uuid.NewString()
+		otherTokenID := uuid.NewString()
+		tokenIDs := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+
+		for _, tokenID := range tokenIDs {
+			assert.NoError(t, r.SetRefreshToken(ctx, userID, tokenID, time.Minute))
+		}
+
+		assert.NoError(t, r.SetRefreshToken(ctx, otherUserID, otherTokenID, time.Minute))
+
+		assert.NoError(t, r.DeleteUserRefreshTokens(ctx, userID))
+
+		for _, tokenID := range tokenIDs {
+			assert.Error(t, r.DeleteRefreshToken(ctx, userID, tokenID))
+		}
+
+		// other users keep their tokens
+		assert.NoError(t, r.DeleteRefreshToken(ctx, otherUserID, otherTokenID))
+	})
+
+	t.Run("Verification tokens are single use", func(t *testing.T) {
+		tokenID := uuid.NewString()
+
+		assert.NoError(t, r.SetVerificationToken(ctx, userID, tokenID, time.Minute))
+
+		// signing out doesn't touch verification tokens
+		assert.NoError(t, r.DeleteUserRefreshTokens(ctx, userID))
+
+		assert.NoError(t, r.DeleteVerificationToken(ctx, userID, tokenID))
+
+		err := r.DeleteVerificationToken(ctx, userID, tokenID)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Password reset tokens are single use and replace each other", func(t *testing.T) {
+		first := uuid.NewString()
+		second := uuid.NewString()
+
+		assert.NoError(t, r.SetPasswordResetToken(ctx, userID, first, time.Minute))
+		assert.NoError(t, r.SetPasswordResetToken(ctx, userID, second, time.Minute))
+
+		// the first link stopped working when the second was sent
+		_, err := r.ConsumePasswordResetToken(ctx, first)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+
+		consumedBy, err := r.ConsumePasswordResetToken(ctx, second)
+		assert.NoError(t, err)
+		assert.Equal(t, userID, consumedBy)
+
+		_, err = r.ConsumePasswordResetToken(ctx, second)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("OIDC states are single use", func(t *testing.T) {
+		state := uuid.NewString()
+		data := &model.OIDCState{
+			Provider: "oidc",
+			Nonce:    uuid.NewString(),
+			Verifier: uuid.NewString(),
+		}
+
+		assert.NoError(t, r.SetOIDCState(ctx, state, data, time.Minute))
+
+		consumed, err := r.ConsumeOIDCState(ctx, state)
+		assert.NoError(t, err)
+		assert.Equal(t, data, consumed)
+
+		_, err = r.ConsumeOIDCState(ctx, state)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+}
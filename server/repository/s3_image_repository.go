@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// s3PartSize bounds the memory an upload of unknown size buffers,
+// profile images fit in a single part
+const s3PartSize = 5 << 20
+
+// s3ImageRepository is an ImageRepository keeping images in a bucket
+// of S3 or an S3 compatible storage like MinIO. The bucket is expected
+// to be publicly readable under BaseURL
+type s3ImageRepository struct {
+	Client  *minio.Client
+	Bucket  string
+	BaseURL string
+}
+
+// NewS3ImageRepository is a factory for initializing S3 Image Repositories
+func NewS3ImageRepository(client *minio.Client, bucket string, baseURL string) model.ImageRepository {
+	return &s3ImageRepository{
+		Client:  client,
+		Bucket:  bucket,
+		BaseURL: baseURL,
+	}
+}
+
+func (r *s3ImageRepository) UpdateProfile(ctx context.Context, objName string, contentType string, image io.Reader) (string, error) {
+	if err := checkImageName(objName); err != nil {
+		return "", err
+	}
+
+	_, err := r.Client.PutObject(ctx, r.Bucket, objName, image, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+		PartSize:    s3PartSize,
+	})
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to upload image", "bucket", r.Bucket, "object", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return r.BaseURL + "/" + objName, nil
+}
+
+func (r *s3ImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	if err := checkImageName(objName); err != nil {
+		return err
+	}
+
+	// deleting a missing object succeeds
+	if err := r.Client.RemoveObject(ctx, r.Bucket, objName, minio.RemoveObjectOptions{}); err != nil {
+		logging.FromContext(ctx).Error("Failed to delete image object", "bucket", r.Bucket, "object", objName, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// PrepareImageBucket creates bucket when it doesn't exist yet and lets
+// anyone read the images in it, existing buckets are left as they are
+func PrepareImageBucket(ctx context.Context, client *minio.Client, bucket string, region string) error {
+	exists, err := client.BucketExists(ctx, bucket)
+
+	if err != nil {
+		return fmt.Errorf("unable to look up bucket %s: %w", bucket, err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: region}); err != nil {
+		return fmt.Errorf("unable to create bucket %s: %w", bucket, err)
+	}
+
+	policy := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::%s/*"]}]}`, bucket)
+
+	if err := client.SetBucketPolicy(ctx, bucket, policy); err != nil {
+		return fmt.Errorf("unable to make bucket %s public: %w", bucket, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestMinIO connects to the MinIO given by MINIO_TEST_ENDPOINT, eg.
+// the minio-remember service from docker-compose:
+//
+//	MINIO_TEST_ENDPOINT="localhost:9000" go test ./repository/...
+//
+// MINIO_TEST_ACCESS_KEY and MINIO_TEST_SECRET_KEY default to the
+// credentials of that service
+func openTestMinIO(t *testing.T) (*minio.Client, string) {
+	t.Helper()
+
+	endpoint := os.Getenv("MINIO_TEST_ENDPOINT")
+
+	if endpoint == "" {
+		t.Skip("MINIO_TEST_ENDPOINT not set, skipping minio tests")
+	}
+
+	accessKey, secretKey := os.Getenv("MINIO_TEST_ACCESS_KEY"), os.Getenv("MINIO_TEST_SECRET_KEY")
+
+	if accessKey == "" {
+		accessKey, secretKey = "minioadmin", "minioadmin"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4(accessKey, secretKey, ""),
+	})
+	require.NoError(t, err)
+
+	return client, "http://" + endpoint
+}
+
+func TestS3ImageRepository(t *testing.T) {
+	client, endpointURL := openTestMinIO(t)
+	ctx := context.Background()
+
+	// a fresh bucket per run, removed with everything in it afterwards
+	bucket := "test-images-" + uuid.NewString()[:8]
+	require.NoError(t, PrepareImageBucket(ctx, client, bucket, "us-east-1"))
+
+	t.Cleanup(func() {
+		for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{}) {
+			client.RemoveObject(ctx, bucket, obj.Key, minio.RemoveObjectOptions{})
+		}
+
+		client.RemoveBucket(ctx, bucket)
+	})
+
+	r := NewS3ImageRepository(client, bucket, endpointURL+"/"+bucket)
+
+	t.Run("Prepare an existing bucket", func(t *testing.T) {
+		assert.NoError(t, PrepareImageBucket(ctx, client, bucket, "us-east-1"))
+	})
+
+	t.Run("Update and delete profile image", func(t *testing.T) {
+		url, err := r.UpdateProfile(ctx, "avatar.png", "image/png", strings.NewReader("image bytes"))
+
+		require.NoError(t, err)
+		assert.Equal(t, endpointURL+"/"+bucket+"/avatar.png", url)
+
+		// served to anyone at the returned URL
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		contents, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "image bytes", string(contents))
+
+		assert.NoError(t, r.DeleteProfile(ctx, "avatar.png"))
+
+		_, err = client.StatObject(ctx, bucket, "avatar.png", minio.StatObjectOptions{})
+		assert.Equal(t, "NoSuchKey", minio.ToErrorResponse(err).Code)
+	})
+
+	t.Run("Delete missing image", func(t *testing.T) {
+		assert.NoError(t, r.DeleteProfile(ctx, "missing.png"))
+	})
+
+	t.Run("Rejects names outside the bucket root", func(t *testing.T) {
+		_, err := r.UpdateProfile(ctx, "../escape.png", "image/png", strings.NewReader("image bytes"))
+		assert.Error(t, err)
+
+		assert.Error(t, r.DeleteProfile(ctx, "nested/escape.png"))
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vuluu2k/remember_fullstack/server/health"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// apiHandler serves the injected router. Until it is set, which is
+// once data sources are ready, every request gets a 503
+type apiHandler struct {
+	router atomic.Pointer[gin.Engine]
+}
+
+func (h *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if router := h.router.Load(); router != nil {
+		router.ServeHTTP(w, r)
+		return
+	}
+
+	err := apperrors.NewServiceUnavailable()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(err.Status())
+	json.NewEncoder(w).Encode(gin.H{
+		"error": err,
+	})
+}
+
+// newMux routes the health probes, which answer from the moment the
+// server listens, next to the API
+func newMux(probe *health.Probe, api http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", probe.Healthz)
+	mux.HandleFunc("/readyz", probe.Readyz)
+	mux.Handle("/", api)
+
+	return mux
+}
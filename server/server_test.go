@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/vuluu2k/remember_fullstack/server/health"
+)
+
+func TestNewMux(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	probe := health.NewProbe(time.Second)
+	api := &apiHandler{}
+	mux := newMux(probe, api)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		mux.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	// while starting
+	assert.Equal(t, http.StatusOK, serve("/healthz").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/readyz").Code)
+
+	rr := serve("/api/account/me")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"error":{"type":"SERVICE_UNAVAILABLE","message":"Service unavailable, try again later."}}`, rr.Body.String())
+
+	// once injected
+	router := gin.New()
+	router.GET("/api/account/me", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	api.router.Store(router)
+	probe.SetState(health.Ready)
+
+	assert.Equal(t, http.StatusOK, serve("/readyz").Code)
+	assert.Equal(t, http.StatusTeapot, serve("/api/account/me").Code)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/google/uuid"
+)
+
+// imageExtensions maps the accepted image types to the file extension
+// of the objects they're stored as
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// newImageObjName returns a unique object name for an image of contentType
+// so a replaced image is never served from a stale cache
+func newImageObjName(contentType string) (string, error) {
+	ext, ok := imageExtensions[contentType]
+
+	if !ok {
+		return "", fmt.Errorf("unsupported image type: %v", contentType)
+	}
+
+	id, err := uuid.NewRandom()
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.String() + ext, nil
+}
+
+// imageObjName extracts the object name from an image URL
+func imageObjName(imageURL string) string {
+	u, err := url.Parse(imageURL)
+
+	if err != nil {
+		return path.Base(imageURL)
+	}
+
+	return path.Base(u.Path)
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/metrics"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"golang.org/x/oauth2"
+)
+
+// invalidOIDCSignIn covers every way a provider's response can fail
+// to check out, the details are only logged
+const invalidOIDCSignIn = "Unable to sign in with the identity provider"
+
+// oidcStateBytes is the entropy of states and nonces
+const oidcStateBytes = 32
+
+// OIDCProviderConfig describes a client registered with an OpenID
+// Connect identity provider
+type OIDCProviderConfig struct {
+	// Name is how the provider appears in routes and identities
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback route the provider sends users back to
+	RedirectURL string
+	Scopes      []string
+}
+
+// OIDCProvider is an identity provider found through OpenID Connect
+// discovery
+type OIDCProvider struct {
+	Name     string
+	OAuth2   *oauth2.Config
+	Verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider fetches the discovery document of c.Issuer, ctx only
+// bounds that request
+func NewOIDCProvider(ctx context.Context, c *OIDCProviderConfig) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, c.Issuer)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover OIDC provider %s: %w", c.Name, err)
+	}
+
+	scopes := c.Scopes
+
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email"}
+	}
+
+	return &OIDCProvider{
+		Name: c.Name,
+		OAuth2: &oauth2.Config{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  c.RedirectURL,
+			Scopes:       scopes,
+		},
+		Verifier: provider.Verifier(&oidc.Config{ClientID: c.ClientID}),
+	}, nil
+}
+
+// OIDCService signs users in with OpenID Connect identity providers
+// using the authorization code flow with PKCE. Identities are linked to
+// the user with the same email once both sides verified it, otherwise a
+// new user without a password is created
+type OIDCService struct {
+	UserRepository      model.UserRepository
+	IdentityRepository  model.IdentityRepository
+	TokenRepository     model.TokenRepository
+	Providers           map[string]*OIDCProvider
+	StateExpirationSecs int64
+}
+
+type OSConfig struct {
+	UserRepository      model.UserRepository
+	IdentityRepository  model.IdentityRepository
+	TokenRepository     model.TokenRepository
+	Providers           []*OIDCProvider
+	StateExpirationSecs int64
+}
+
+func NewOIDCService(c *OSConfig) model.OIDCService {
+	providers := make(map[string]*OIDCProvider, len(c.Providers))
+
+	for _, p := range c.Providers {
+		providers[p.Name] = p
+	}
+
+	return &OIDCService{
+		UserRepository:      c.UserRepository,
+		IdentityRepository:  c.IdentityRepository,
+		TokenRepository:     c.TokenRepository,
+		Providers:           providers,
+		StateExpirationSecs: c.StateExpirationSecs,
+	}
+}
+
+// oidcClaims are the ID token claims users are identified by
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+func (s *OIDCService) AuthURL(ctx context.Context, provider string) (string, string, error) {
+	p, ok := s.Providers[provider]
+
+	if !ok {
+		return "", "", apperrors.NewNotFound("provider", provider)
+	}
+
+	state, err := randomOIDCValue()
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to generate OIDC state", "err", err)
+		return "", "", apperrors.NewInternal()
+	}
+
+	nonce, err := randomOIDCValue()
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to generate OIDC nonce", "err", err)
+		return "", "", apperrors.NewInternal()
+	}
+
+	data := &model.OIDCState{
+		Provider: p.Name,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+	}
+
+	expiresIn := time.Duration(s.StateExpirationSecs) * time.Second
+
+	if err := s.TokenRepository.SetOIDCState(ctx, state, data, expiresIn); err != nil {
+		return "", "", err
+	}
+
+	authURL := p.OAuth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(data.Verifier))
+
+	return authURL, state, nil
+}
+
+func (s *OIDCService) Callback(ctx context.Context, provider string, state string, code string) (*model.User, error) {
+	p, ok := s.Providers[provider]
+
+	if !ok {
+		return nil, apperrors.NewNotFound("provider", provider)
+	}
+
+	data, err := s.TokenRepository.ConsumeOIDCState(ctx, state)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Provider != p.Name {
+		logging.FromContext(ctx).Info("OIDC state was issued for another provider", "provider", p.Name, "stateProvider", data.Provider)
+		return nil, apperrors.NewAuthorization("Invalid sign in state")
+	}
+
+	token, err := p.OAuth2.Exchange(ctx, code, oauth2.VerifierOption(data.Verifier))
+
+	if err != nil {
+		logging.FromContext(ctx).Info("Unable to exchange OIDC code", "provider", p.Name, "err", err)
+		return nil, apperrors.NewAuthorization(invalidOIDCSignIn)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+
+	if !ok {
+		logging.FromContext(ctx).Info("OIDC token response has no id_token", "provider", p.Name)
+		return nil, apperrors.NewAuthorization(invalidOIDCSignIn)
+	}
+
+	idToken, err := p.Verifier.Verify(ctx, rawIDToken)
+
+	if err != nil {
+		logging.FromContext(ctx).Info("Unable to verify OIDC id token", "provider", p.Name, "err", err)
+		return nil, apperrors.NewAuthorization(invalidOIDCSignIn)
+	}
+
+	if idToken.Nonce != data.Nonce {
+		logging.FromContext(ctx).Info("OIDC id token nonce does not match", "provider", p.Name)
+		return nil, apperrors.NewAuthorization(invalidOIDCSignIn)
+	}
+
+	var claims oidcClaims
+
+	if err := idToken.Claims(&claims); err != nil {
+		logging.FromContext(ctx).Info("Unable to parse OIDC id token claims", "provider", p.Name, "err", err)
+		return nil, apperrors.NewAuthorization(invalidOIDCSignIn)
+	}
+
+	identity, err := s.IdentityRepository.FindByProviderSubject(ctx, p.Name, idToken.Subject)
+
+	// a lockout only stops password guessing, it doesn't apply here
+	if err == nil {
+		return s.UserRepository.FindById(ctx, identity.UID)
+	}
+
+	if apperrors.Status(err) != http.StatusNotFound {
+		return nil, err
+	}
+
+	return s.link(ctx, p.Name, idToken.Subject, &claims)
+}
+
+// link ties a new identity to the user with its email, creating the
+// user when there is none
+func (s *OIDCService) link(ctx context.Context, provider string, subject string, claims *oidcClaims) (*model.User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		logging.FromContext(ctx).Info("OIDC identity has no verified email", "provider", provider)
+		return nil, apperrors.NewAuthorization("The identity provider has not verified your email")
+	}
+
+	u, err := s.UserRepository.FindByEmail(ctx, claims.Email)
+
+	switch {
+	case err == nil:
+		// whoever signed up with an email they never verified may not
+		// own it, linking would let them into the provider user's account
+		if !u.EmailVerified {
+			logging.FromContext(ctx).Info("Refused linking OIDC identity to an unverified user", "provider", provider, "uid", u.UID)
+			return nil, apperrors.NewConflict("email", claims.Email)
+		}
+	case apperrors.Status(err) == http.StatusNotFound:
+		u = &model.User{
+			Email:         claims.Email,
+			EmailVerified: true,
+		}
+
+		if err := s.UserRepository.Create(ctx, u); err != nil {
+			return nil, err
+		}
+
+		metrics.SignUps.Inc()
+	default:
+		return nil, err
+	}
+
+	identity := &model.Identity{
+		Provider: provider,
+		Subject:  subject,
+		UID:      u.UID,
+		Email:    claims.Email,
+	}
+
+	if err := s.IdentityRepository.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func randomOIDCValue() (string, error) {
+	b := make([]byte, oidcStateBytes)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
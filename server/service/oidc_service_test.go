@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+const fakeOIDCClientID = "remember-test-client"
+
+// fakeOIDCGrant is what the fake provider remembers about an
+// authorization code it handed out
+type fakeOIDCGrant struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+// fakeOIDCProvider is an OpenID Connect provider serving discovery,
+// keys and tokens, which signs users in without asking them anything
+type fakeOIDCProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]*fakeOIDCGrant
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	f := &fakeOIDCProvider{
+		key:    key,
+		grants: map[string]*fakeOIDCGrant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", f.discovery)
+	mux.HandleFunc("/jwks", f.jwks)
+	mux.HandleFunc("/token", f.token)
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                f.URL,
+		"authorization_endpoint":                f.URL + "/authorize",
+		"token_endpoint":                        f.URL + "/token",
+		"jwks_uri":                              f.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (f *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (f *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	grant, ok := f.grants[r.PostFormValue("code")]
+	delete(f.grants, r.PostFormValue("code"))
+	f.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   f.URL,
+		"aud":   fakeOIDCClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": grant.nonce,
+	}
+
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+
+	idToken, _ := token.SignedString(f.key)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "fake-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+// authorize signs a user with claims in at authURL, returning the
+// state and code the provider sends them back with
+func (f *fakeOIDCProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (string, string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	query := parsed.Query()
+	assert.Equal(t, f.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, fakeOIDCClientID, query.Get("client_id"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	code := uuid.NewString()
+
+	f.mu.Lock()
+	f.grants[code] = &fakeOIDCGrant{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		claims:    claims,
+	}
+	f.mu.Unlock()
+
+	return query.Get("state"), code
+}
+
+func TestOIDCService(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+
+	provider, err := NewOIDCProvider(context.Background(), &OIDCProviderConfig{
+		Name:         "fake",
+		Issuer:       fake.URL,
+		ClientID:     fakeOIDCClientID,
+		ClientSecret: "fake-client-secret",
+		RedirectURL:  "http://dev2000.test/api/account/oidc/fake/callback",
+	})
+	require.NoError(t, err)
+
+	uid, _ := uuid.NewRandom()
+	email := "vuluu040320@gmail.com"
+
+	// signIn runs the browser's part of a sign in with the fake
+	// provider, returning the state and code of its callback
+	signIn := func(t *testing.T, s model.OIDCService, mockTokenRepository *mocks.MockTokenRepository, claims jwt.MapClaims) (string, string) {
+		t.Helper()
+
+		var stored *model.OIDCState
+
+		mockTokenRepository.On("SetOIDCState", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.OIDCState"), 600*time.Second).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(2).(*model.OIDCState)
+			}).Return(nil).Once()
+
+		authURL, state, err := s.AuthURL(context.TODO(), "fake")
+		require.NoError(t, err)
+
+		returnedState, code := fake.authorize(t, authURL, claims)
+		require.Equal(t, state, returnedState)
+
+		mockTokenRepository.On("ConsumeOIDCState", mock.Anything, state).Return(stored, nil).Once()
+
+		return state, code
+	}
+
+	verifiedClaims := func(subject string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":            subject,
+			"email":          email,
+			"email_verified": true,
+		}
+	}
+
+	t.Run("Creates a user", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+		subject := uuid.NewString()
+
+		state, code := signIn(t, s, mockTokenRepository, verifiedClaims(subject))
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "fake", subject).Return(nil, apperrors.NewNotFound("identity", subject))
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+		mockUserRepository.On("Create", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.Email == email && u.EmailVerified && u.Password == ""
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.User).UID = uid
+		}).Return(nil)
+		mockIdentityRepository.On("Create", mock.Anything, &model.Identity{
+			Provider: "fake",
+			Subject:  subject,
+			UID:      uid,
+			Email:    email,
+		}).Return(nil)
+
+		u, err := s.Callback(context.TODO(), "fake", state, code)
+
+		require.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		assert.True(t, u.EmailVerified)
+
+		mockUserRepository.AssertExpectations(t)
+		mockIdentityRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Links a verified user", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+		subject := uuid.NewString()
+		existing := &model.User{UID: uid, Email: email, Password: "hashed", EmailVerified: true}
+
+		state, code := signIn(t, s, mockTokenRepository, verifiedClaims(subject))
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "fake", subject).Return(nil, apperrors.NewNotFound("identity", subject))
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(existing, nil)
+		mockIdentityRepository.On("Create", mock.Anything, mock.MatchedBy(func(identity *model.Identity) bool {
+			return identity.UID == uid && identity.Subject == subject
+		})).Return(nil)
+
+		u, err := s.Callback(context.TODO(), "fake", state, code)
+
+		require.NoError(t, err)
+		assert.Equal(t, existing, u)
+
+		mockUserRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		mockIdentityRepository.AssertExpectations(t)
+	})
+
+	t.Run("Signs in a linked identity", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+		subject := uuid.NewString()
+		existing := &model.User{UID: uid, Email: "changed@remember.test"}
+
+		// the email may have changed since linking
+		state, code := signIn(t, s, mockTokenRepository, jwt.MapClaims{"sub": subject})
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "fake", subject).Return(&model.Identity{Provider: "fake", Subject: subject, UID: uid}, nil)
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(existing, nil)
+
+		u, err := s.Callback(context.TODO(), "fake", state, code)
+
+		require.NoError(t, err)
+		assert.Equal(t, existing, u)
+
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+		mockIdentityRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unverified provider email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+		subject := uuid.NewString()
+
+		claims := verifiedClaims(subject)
+		claims["email_verified"] = false
+
+		state, code := signIn(t, s, mockTokenRepository, claims)
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "fake", subject).Return(nil, apperrors.NewNotFound("identity", subject))
+
+		u, err := s.Callback(context.TODO(), "fake", state, code)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Refuses linking an unverified user", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+		subject := uuid.NewString()
+
+		state, code := signIn(t, s, mockTokenRepository, verifiedClaims(subject))
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "fake", subject).Return(nil, apperrors.NewNotFound("identity", subject))
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: "hashed"}, nil)
+
+		u, err := s.Callback(context.TODO(), "fake", state, code)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewConflict("email", email), err)
+
+		mockIdentityRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Code exchanged without its verifier", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+		subject := uuid.NewString()
+
+		state, code := signIn(t, s, mockTokenRepository, verifiedClaims(subject))
+
+		// a stolen code is useless without the verifier of its sign in
+		mockTokenRepository.ExpectedCalls = nil
+		mockTokenRepository.On("ConsumeOIDCState", mock.Anything, state).Return(&model.OIDCState{Provider: "fake", Verifier: "stolen"}, nil)
+
+		u, err := s.Callback(context.TODO(), "fake", state, code)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewAuthorization(invalidOIDCSignIn), err)
+
+		mockIdentityRepository.AssertNotCalled(t, "FindByProviderSubject", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown or used state", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+
+		mockTokenRepository.On("ConsumeOIDCState", mock.Anything, "used").Return(nil, apperrors.NewAuthorization("Invalid sign in state"))
+
+		u, err := s.Callback(context.TODO(), "fake", "used", "code")
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("State of another provider", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+
+		mockTokenRepository.On("ConsumeOIDCState", mock.Anything, "other").Return(&model.OIDCState{Provider: "other"}, nil)
+
+		u, err := s.Callback(context.TODO(), "fake", "other", "code")
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewAuthorization("Invalid sign in state"), err)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		s := NewOIDCService(&OSConfig{
+			UserRepository:      mockUserRepository,
+			IdentityRepository:  mockIdentityRepository,
+			TokenRepository:     mockTokenRepository,
+			Providers:           []*OIDCProvider{provider},
+			StateExpirationSecs: 600,
+		})
+
+		_, _, err := s.AuthURL(context.TODO(), "missing")
+		assert.Equal(t, apperrors.NewNotFound("provider", "missing"), err)
+
+		_, err = s.Callback(context.TODO(), "missing", "state", "code")
+		assert.Equal(t, apperrors.NewNotFound("provider", "missing"), err)
+
+		mockTokenRepository.AssertNotCalled(t, "SetOIDCState", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
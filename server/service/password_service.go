@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// invalidResetToken covers unknown, used and expired reset tokens
+const invalidResetToken = "Password reset token is invalid or has expired"
+
+// resetTokenBytes is the entropy of a password reset token
+const resetTokenBytes = 32
+
+// resetMailTimeout bounds storing and emailing a reset link after the
+// request asking for it has been answered
+const resetMailTimeout = 30 * time.Second
+
+// PasswordService lets users who forgot their password set a new one
+// through an emailed link. Only the hash of each link's token is
+// stored, so a leaked store can't be used to reset passwords
+type PasswordService struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	Mailer          model.Mailer
+	ExpirationSecs  int64
+	// ResetURL is where links point, with the token in the token
+	// query parameter
+	ResetURL string
+	Hash     HashParams
+	// sending tracks reset links still being emailed
+	sending sync.WaitGroup
+}
+
+type PSConfig struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	Mailer          model.Mailer
+	ExpirationSecs  int64
+	ResetURL        string
+	// Hash is what new passwords are hashed with
+	Hash HashParams
+}
+
+func NewPasswordService(c *PSConfig) model.PasswordService {
+	return &PasswordService{
+		UserRepository:  c.UserRepository,
+		TokenRepository: c.TokenRepository,
+		Mailer:          c.Mailer,
+		ExpirationSecs:  c.ExpirationSecs,
+		ResetURL:        c.ResetURL,
+		Hash:            c.Hash,
+	}
+}
+
+// hashResetToken is how reset tokens are stored
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// ForgotPassword emails the link after returning, so that known and
+// unknown emails take the same time to answer and can't be told apart.
+// Failing to send it is only logged
+func (s *PasswordService) ForgotPassword(ctx context.Context, email string) error {
+	u, err := s.UserRepository.FindByEmail(ctx, email)
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			logging.FromContext(ctx).Info("Password reset requested for unknown email")
+			return nil
+		}
+
+		return err
+	}
+
+	// ctx ends with the request, only its logger is carried over
+	sendCtx, cancel := context.WithTimeout(logging.WithLogger(context.Background(), logging.FromContext(ctx)), resetMailTimeout)
+
+	s.sending.Add(1)
+
+	go func() {
+		defer s.sending.Done()
+		defer cancel()
+
+		if err := s.sendResetLink(sendCtx, u); err != nil {
+			logging.FromContext(sendCtx).Error("Failed to send password reset email", "uid", u.UID, "err", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *PasswordService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		s.sending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendResetLink stores a new reset token of u and emails the link with it
+func (s *PasswordService) sendResetLink(ctx context.Context, u *model.User) error {
+	b := make([]byte, resetTokenBytes)
+
+	if _, err := rand.Read(b); err != nil {
+		logging.FromContext(ctx).Error("Unable to generate password reset token", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	expiresIn := time.Duration(s.ExpirationSecs) * time.Second
+
+	if err := s.TokenRepository.SetPasswordResetToken(ctx, u.UID.String(), hashResetToken(token), expiresIn); err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.ResetURL)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Invalid password reset URL", "url", s.ResetURL, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return s.Mailer.Send(ctx, &model.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Remember account. Choose a new password by opening the link below within %s:\n\n%s\n\nIf it wasn't you, you can ignore this email, your password stays the same.\n",
+			humanDuration(expiresIn), link.String()),
+	})
+}
+
+func (s *PasswordService) ResetPassword(ctx context.Context, tokenString string, password string) error {
+	userID, err := s.TokenRepository.ConsumePasswordResetToken(ctx, hashResetToken(tokenString))
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusUnauthorized {
+			return apperrors.NewAuthorization(invalidResetToken)
+		}
+
+		return err
+	}
+
+	uid, err := uuid.Parse(userID)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Password reset token belongs to an invalid uid", "uid", userID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	pw, err := hashPassword(password, s.Hash)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to hash password", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, uid, pw); err != nil {
+		return err
+	}
+
+	// whoever knew the old password is signed out
+	if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, userID); err != nil {
+		logging.FromContext(ctx).Error("Unable to revoke refresh tokens after password reset", "uid", uid, "err", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/mailer"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestPasswordService(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	u := &model.User{
+		UID:      uid,
+		Email:    "vuluu040320@gmail.com",
+		Password: "oldhashedpassword",
+	}
+
+	t.Run("Emails a link whose token is stored hashed", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		ps := NewPasswordService(&PSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			ExpirationSecs:  1800,
+			ResetURL:        "http://dev2000.test/reset-password",
+		})
+
+		var storedHash string
+
+		mockUserRepository.On("FindByEmail", mock.Anything, u.Email).Return(u, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, uid.String(), mock.AnythingOfType("string"), 30*time.Minute).
+			Run(func(args mock.Arguments) {
+				storedHash = args.String(2)
+			}).Return(nil)
+
+		require.NoError(t, ps.ForgotPassword(context.TODO(), u.Email))
+		require.NoError(t, ps.Wait(context.TODO()))
+
+		messages := memoryMailer.Messages()
+		require.Len(t, messages, 1)
+		assert.Equal(t, u.Email, messages[0].To)
+		assert.Contains(t, messages[0].Body, "http://dev2000.test/reset-password?token=")
+		assert.Contains(t, messages[0].Body, "within 30 minutes:")
+
+		token := sentToken(t, memoryMailer)
+		assert.NotEmpty(t, token)
+		assert.NotEqual(t, token, storedHash)
+		assert.Equal(t, hashResetToken(token), storedHash)
+	})
+
+	t.Run("Unknown email sends nothing", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		ps := NewPasswordService(&PSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			ExpirationSecs:  1800,
+			ResetURL:        "http://dev2000.test/reset-password",
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "nobody@remember.test").Return(nil, apperrors.NewNotFound("email", "nobody@remember.test"))
+
+		assert.NoError(t, ps.ForgotPassword(context.TODO(), "nobody@remember.test"))
+		require.NoError(t, ps.Wait(context.TODO()))
+
+		assert.Empty(t, memoryMailer.Messages())
+		mockTokenRepository.AssertNotCalled(t, "SetPasswordResetToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		ps := NewPasswordService(&PSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			ExpirationSecs:  1800,
+			ResetURL:        "http://dev2000.test/reset-password",
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, u.Email).Return(nil, apperrors.NewInternal())
+
+		err := ps.ForgotPassword(context.TODO(), u.Email)
+		assert.Equal(t, apperrors.NewInternal(), err)
+		assert.Empty(t, memoryMailer.Messages())
+	})
+
+	t.Run("Storing the token fails", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		ps := NewPasswordService(&PSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			ExpirationSecs:  1800,
+			ResetURL:        "http://dev2000.test/reset-password",
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, u.Email).Return(u, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+
+		// answered like any other email before the token is stored
+		assert.NoError(t, ps.ForgotPassword(context.TODO(), u.Email))
+		require.NoError(t, ps.Wait(context.TODO()))
+
+		assert.Empty(t, memoryMailer.Messages())
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Wait gives up when ctx is done", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		ps := NewPasswordService(&PSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			ExpirationSecs:  1800,
+			ResetURL:        "http://dev2000.test/reset-password",
+		})
+
+		release := make(chan struct{})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, u.Email).Return(u, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, uid.String(), mock.AnythingOfType("string"), 30*time.Minute).
+			Run(func(args mock.Arguments) {
+				<-release
+			}).Return(nil)
+
+		require.NoError(t, ps.ForgotPassword(context.TODO(), u.Email))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, ps.Wait(ctx), context.DeadlineExceeded)
+
+		close(release)
+
+		require.NoError(t, ps.Wait(context.TODO()))
+		assert.Len(t, memoryMailer.Messages(), 1)
+	})
+
+	t.Run("Resets the password and signs out everywhere", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		ps := NewPasswordService(&PSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			ExpirationSecs:  1800,
+			ResetURL:        "http://dev2000.test/reset-password",
+		})
+
+		var newHash string
+
+		mockTokenRepository.On("ConsumePasswordResetToken", mock.Anything, hashResetToken("avalidtoken")).Return(uid.String(), nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				newHash = args.String(2)
+			}).Return(nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+
+		require.NoError(t, ps.ResetPassword(context.TODO(), "avalidtoken", "newpassword"))
+
+		match, err := comparePasswords(newHash, "newpassword", HashParams{})
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid or used token", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		ps := NewPasswordService(&PSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			ExpirationSecs:  1800,
+			ResetURL:        "http://dev2000.test/reset-password",
+		})
+
+		mockTokenRepository.On("ConsumePasswordResetToken", mock.Anything, hashResetToken("usedtoken")).Return("", apperrors.NewAuthorization("Invalid password reset token"))
+
+		err := ps.ResetPassword(context.TODO(), "usedtoken", "newpassword")
+		assert.EqualError(t, err, invalidResetToken)
+
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		mockTokenRepository.AssertNotCalled(t, "DeleteUserRefreshTokens", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt output sizes, see https://pkg.go.dev/golang.org/x/crypto/scrypt
+const (
+	scryptKeyLen = 32
+	saltLen      = 32
+)
+
+// scryptPrefix starts hashes stored as
+// "$scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>", salt and hash in
+// unpadded base64
+const scryptPrefix = "$scrypt$"
+
+// HashParams are the scrypt costs passwords are hashed with. Zero
+// fields take the defaults. Hashes record the costs they were made
+// with, so raising them upgrades each password at its next sign in
+type HashParams struct {
+	N int
+	R int
+	P int
+}
+
+var (
+	defaultHashParams = HashParams{N: 32768, R: 8, P: 1}
+
+	// legacyHashParams made the "<hash>.<salt>" hex hashes stored
+	// before hashes recorded their costs
+	legacyHashParams = HashParams{N: 32768, R: 8, P: 1}
+)
+
+func (p HashParams) withDefaults() HashParams {
+	if p.N == 0 {
+		p.N = defaultHashParams.N
+	}
+
+	if p.R == 0 {
+		p.R = defaultHashParams.R
+	}
+
+	if p.P == 0 {
+		p.P = defaultHashParams.P
+	}
+
+	return p
+}
+
+// Validate reports costs scrypt refuses
+func (p HashParams) Validate() error {
+	p = p.withDefaults()
+
+	if p.N <= 1 || p.N&(p.N-1) != 0 {
+		return fmt.Errorf("scrypt N must be a power of two greater than 1, got %v", p.N)
+	}
+
+	if p.R < 0 || p.P < 0 || uint64(p.R)*uint64(p.P) >= 1<<30 {
+		return fmt.Errorf("scrypt r * p must be positive and below 2^30, got r=%v p=%v", p.R, p.P)
+	}
+
+	return nil
+}
+
+// weakerThan reports whether any cost of p is below want
+func (p HashParams) weakerThan(want HashParams) bool {
+	return p.N < want.N || p.R < want.R || p.P < want.P
+}
+
+var (
+	dummyHashMu sync.Mutex
+	dummyHashes = map[HashParams]string{}
+)
+
+// dummyPasswordHash returns a valid hash to compare against
+// when there is no stored password to check
+func dummyPasswordHash(p HashParams) string {
+	p = p.withDefaults()
+
+	dummyHashMu.Lock()
+	defer dummyHashMu.Unlock()
+
+	if _, ok := dummyHashes[p]; !ok {
+		dummyHashes[p], _ = hashPassword("remember-dummy-password", p)
+	}
+
+	return dummyHashes[p]
+}
+
+// hashPassword salts and hashes password with scrypt using p
+func hashPassword(password string, p HashParams) (string, error) {
+	p = p.withDefaults()
+
+	salt := make([]byte, saltLen)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	shash, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, scryptKeyLen)
+
+	if err != nil {
+		return "", err
+	}
+
+	hashedPW := fmt.Sprintf("%sln=%d,r=%d,p=%d$%s$%s",
+		scryptPrefix,
+		bits.TrailingZeros(uint(p.N)), p.R, p.P,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(shash))
+
+	return hashedPW, nil
+}
+
+// comparePasswords reports whether suppliedPassword matches
+// storedPassword, which is either made by hashPassword, a legacy hex
+// scrypt hash or an imported bcrypt hash. Users created through an
+// identity provider have no stored password and never match, taking
+// as long as a hash made with p, the costs other users' hashes have
+func comparePasswords(storedPassword string, suppliedPassword string, p HashParams) (bool, error) {
+	if storedPassword == "" {
+		comparePasswords(dummyPasswordHash(p), suppliedPassword, p)
+		return false, nil
+	}
+
+	if isBcryptHash(storedPassword) {
+		err := bcrypt.CompareHashAndPassword([]byte(storedPassword), []byte(suppliedPassword))
+
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+
+		return err == nil, err
+	}
+
+	stored, salt, hash, err := parseScryptHash(storedPassword)
+
+	if err != nil {
+		return false, err
+	}
+
+	shash, err := scrypt.Key([]byte(suppliedPassword), salt, stored.N, stored.R, stored.P, len(hash))
+
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(shash, hash) == 1, nil
+}
+
+// needsRehash reports whether storedPassword should be replaced by a
+// hash made with p, because it uses another algorithm or weaker costs
+func needsRehash(storedPassword string, p HashParams) bool {
+	if !strings.HasPrefix(storedPassword, scryptPrefix) {
+		return true
+	}
+
+	stored, salt, hash, err := parseScryptHash(storedPassword)
+
+	if err != nil {
+		return true
+	}
+
+	return stored.weakerThan(p.withDefaults()) || len(salt) < saltLen || len(hash) < scryptKeyLen
+}
+
+func isBcryptHash(storedPassword string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(storedPassword, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseScryptHash splits a hash made by hashPassword, or a legacy
+// "<hash>.<salt>" hex hash, into its costs, salt and hash
+func parseScryptHash(storedPassword string) (HashParams, []byte, []byte, error) {
+	if !strings.HasPrefix(storedPassword, scryptPrefix) {
+		return parseLegacyScryptHash(storedPassword)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(storedPassword, scryptPrefix), "$")
+
+	if len(parts) != 3 {
+		return HashParams{}, nil, nil, fmt.Errorf("invalid stored password format")
+	}
+
+	var ln uint
+	var p HashParams
+
+	if _, err := fmt.Sscanf(parts[0], "ln=%d,r=%d,p=%d", &ln, &p.R, &p.P); err != nil {
+		return HashParams{}, nil, nil, fmt.Errorf("unable to parse stored password costs: %w", err)
+	}
+
+	if ln == 0 || ln >= 63 {
+		return HashParams{}, nil, nil, fmt.Errorf("invalid stored password cost ln=%d", ln)
+	}
+
+	p.N = 1 << ln
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+
+	if err != nil {
+		return HashParams{}, nil, nil, fmt.Errorf("unable to decode stored password salt: %w", err)
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return HashParams{}, nil, nil, fmt.Errorf("unable to decode stored password hash: %w", err)
+	}
+
+	return p, salt, hash, nil
+}
+
+func parseLegacyScryptHash(storedPassword string) (HashParams, []byte, []byte, error) {
+	pwsalt := strings.Split(storedPassword, ".")
+
+	if len(pwsalt) != 2 {
+		return HashParams{}, nil, nil, fmt.Errorf("invalid stored password format")
+	}
+
+	hash, err := hex.DecodeString(pwsalt[0])
+
+	if err != nil {
+		return HashParams{}, nil, nil, fmt.Errorf("unable to decode stored password hash: %w", err)
+	}
+
+	salt, err := hex.DecodeString(pwsalt[1])
+
+	if err != nil {
+		return HashParams{}, nil, nil, fmt.Errorf("unable to decode stored password salt: %w", err)
+	}
+
+	return legacyHashParams, salt, hash, nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// legacyHash makes a hash in the "<hash>.<salt>" hex format used
+// before hashes recorded their costs
+func legacyHash(t *testing.T, password string) string {
+	t.Helper()
+
+	salt := []byte(strings.Repeat("s", saltLen))
+
+	shash, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, scryptKeyLen)
+	require.NoError(t, err)
+
+	return hex.EncodeToString(shash) + "." + hex.EncodeToString(salt)
+}
+
+func TestPasswords(t *testing.T) {
+	t.Run("Hash and compare", func(t *testing.T) {
+		hashed, err := hashPassword("SuperKeyPass123", HashParams{})
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, "SuperKeyPass123", hashed)
+		assert.True(t, strings.HasPrefix(hashed, "$scrypt$ln=15,r=8,p=1$"))
+
+		match, err := comparePasswords(hashed, "SuperKeyPass123", HashParams{})
+
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = comparePasswords(hashed, "WrongPass123", HashParams{})
+
+		assert.NoError(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("Unique salt", func(t *testing.T) {
+		first, err := hashPassword("SuperKeyPass123", HashParams{})
+		assert.NoError(t, err)
+
+		second, err := hashPassword("SuperKeyPass123", HashParams{})
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Hashes record their costs", func(t *testing.T) {
+		hashed, err := hashPassword("SuperKeyPass123", HashParams{N: 1024, R: 4, P: 2})
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(hashed, "$scrypt$ln=10,r=4,p=2$"))
+
+		// compared with the recorded costs, not the defaults
+		match, err := comparePasswords(hashed, "SuperKeyPass123", HashParams{})
+		assert.NoError(t, err)
+		assert.True(t, match)
+	})
+
+	t.Run("Legacy hex hashes", func(t *testing.T) {
+		hashed := legacyHash(t, "SuperKeyPass123")
+
+		match, err := comparePasswords(hashed, "SuperKeyPass123", HashParams{})
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = comparePasswords(hashed, "WrongPass123", HashParams{})
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		assert.True(t, needsRehash(hashed, HashParams{}))
+	})
+
+	t.Run("Imported bcrypt hashes", func(t *testing.T) {
+		b, err := bcrypt.GenerateFromPassword([]byte("SuperKeyPass123"), bcrypt.MinCost)
+		require.NoError(t, err)
+
+		hashed := string(b)
+
+		match, err := comparePasswords(hashed, "SuperKeyPass123", HashParams{})
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = comparePasswords(hashed, "WrongPass123", HashParams{})
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		assert.True(t, needsRehash(hashed, HashParams{}))
+	})
+
+	t.Run("Rehash only below the wanted costs", func(t *testing.T) {
+		hashed, err := hashPassword("SuperKeyPass123", HashParams{N: 1024, R: 8, P: 1})
+		require.NoError(t, err)
+
+		assert.False(t, needsRehash(hashed, HashParams{N: 1024, R: 8, P: 1}))
+		assert.False(t, needsRehash(hashed, HashParams{N: 512, R: 8, P: 1}))
+		assert.True(t, needsRehash(hashed, HashParams{N: 2048, R: 8, P: 1}))
+		assert.True(t, needsRehash(hashed, HashParams{N: 1024, R: 16, P: 1}))
+		assert.True(t, needsRehash(hashed, HashParams{N: 1024, R: 8, P: 2}))
+		assert.True(t, needsRehash(hashed, HashParams{}))
+	})
+
+	t.Run("No stored password", func(t *testing.T) {
+		match, err := comparePasswords("", "SuperKeyPass123", HashParams{})
+
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		match, err = comparePasswords("", "", HashParams{})
+
+		assert.NoError(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("No stored password with configured costs", func(t *testing.T) {
+		p := HashParams{N: 1024, R: 8, P: 1}
+		match, err := comparePasswords("", "SuperKeyPass123", p)
+
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		// timed like the hashes made with p
+		dummyHashMu.Lock()
+		dummy := dummyHashes[p]
+		dummyHashMu.Unlock()
+
+		assert.NotEmpty(t, dummy)
+		assert.False(t, needsRehash(dummy, p))
+	})
+
+	t.Run("Invalid stored format", func(t *testing.T) {
+		for _, stored := range []string{
+			"not-a-hash",
+			"$scrypt$ln=15,r=8,p=1$notbase64!$notbase64!",
+			"$scrypt$ln=99,r=8,p=1$c2FsdA$aGFzaA",
+			"$scrypt$r=8,p=1$c2FsdA$aGFzaA",
+		} {
+			match, err := comparePasswords(stored, "SuperKeyPass123", HashParams{})
+
+			assert.Error(t, err, stored)
+			assert.False(t, match)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, HashParams{}.Validate())
+		assert.NoError(t, HashParams{N: 65536, R: 8, P: 2}.Validate())
+		assert.Error(t, HashParams{N: 1000}.Validate())
+		assert.Error(t, HashParams{N: 1}.Validate())
+		assert.Error(t, HashParams{R: 1 << 16, P: 1 << 14}.Validate())
+	})
+}
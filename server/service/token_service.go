@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/metrics"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// TokenService used for injecting an implementation of TokenRepository
+// for use in service methods along with keys and secrets for
+// signing JWTs
+type TokenService struct {
+	TokenRepository       model.TokenRepository
+	PrivKey               *rsa.PrivateKey
+	PubKey                *rsa.PublicKey
+	RefreshSecret         string
+	IDExpirationSecs      int64
+	RefreshExpirationSecs int64
+}
+
+// TSConfig will hold repositories and keys that will be injected into
+// this service layer
+type TSConfig struct {
+	TokenRepository       model.TokenRepository
+	PrivKey               *rsa.PrivateKey
+	PubKey                *rsa.PublicKey
+	RefreshSecret         string
+	IDExpirationSecs      int64
+	RefreshExpirationSecs int64
+}
+
+// NewTokenService is a factory function for
+// initializing a TokenService with its repository layer dependencies
+func NewTokenService(c *TSConfig) model.TokenService {
+	return &TokenService{
+		TokenRepository:       c.TokenRepository,
+		PrivKey:               c.PrivKey,
+		PubKey:                c.PubKey,
+		RefreshSecret:         c.RefreshSecret,
+		IDExpirationSecs:      c.IDExpirationSecs,
+		RefreshExpirationSecs: c.RefreshExpirationSecs,
+	}
+}
+
+// NewPairFromUser creates fresh id and refresh tokens for the current user
+// If a previous token is included, the previous token is removed from
+// the tokens repository. Presenting a previous token that is no longer
+// stored means it was already rotated, so every refresh token of the
+// user is revoked as it has likely been stolen
+func (s *TokenService) NewPairFromUser(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenPair, error) {
+	if prevTokenID != "" {
+		if err := s.TokenRepository.DeleteRefreshToken(ctx, u.UID.String(), prevTokenID); err != nil {
+			if apperrors.Status(err) != http.StatusUnauthorized {
+				logging.FromContext(ctx).Error("Could not delete previous refreshToken", "uid", u.UID, "token_id", prevTokenID, "err", err)
+				return nil, err
+			}
+
+			logging.FromContext(ctx).Warn("Refresh token reuse detected. Revoking all refresh tokens", "uid", u.UID, "token_id", prevTokenID)
+			metrics.RefreshTokenReuses.Inc()
+
+			if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, u.UID.String()); err != nil {
+				logging.FromContext(ctx).Error("Could not revoke refresh tokens", "uid", u.UID, "err", err)
+				return nil, err
+			}
+
+			return nil, apperrors.NewAuthorization("Refresh token has been revoked")
+		}
+	}
+
+	idToken, err := generateIDToken(u, s.PrivKey, s.IDExpirationSecs)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Error generating idToken", "uid", u.UID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	refreshToken, err := generateRefreshToken(u.UID, s.RefreshSecret, s.RefreshExpirationSecs)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Error generating refreshToken", "uid", u.UID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	// set freshly minted refresh token to valid list
+	if err := s.TokenRepository.SetRefreshToken(ctx, u.UID.String(), refreshToken.ID.String(), refreshToken.ExpiresIn); err != nil {
+		logging.FromContext(ctx).Error("Error storing tokenID", "uid", u.UID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if prevTokenID != "" {
+		metrics.TokenRefreshes.Inc()
+	}
+
+	return &model.TokenPair{
+		TokenID:      idToken,
+		RefreshToken: refreshToken.SS,
+	}, nil
+}
+
+// Signout reaches out to the repository layer to delete all valid tokens for a user
+func (s *TokenService) Signout(ctx context.Context, uid uuid.UUID) error {
+	return s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String())
+}
+
+// ValidateIDToken validates the id token jwt string
+// It returns the user extract from the IDTokenCustomClaims
+func (s *TokenService) ValidateIDToken(tokenString string) (*model.User, error) {
+	claims, err := validateIDToken(tokenString, s.PubKey) // uses public RSA key
+
+	// We'll just return unauthorized error in all instances of failing to verify user
+	if err != nil {
+		slog.Info("Unable to validate or parse idToken", "err", err)
+		return nil, apperrors.NewAuthorization("Unable to verify user from idToken")
+	}
+
+	return claims.User, nil
+}
+
+// ValidateRefreshToken checks to make sure the JWT provided by a string is valid
+// and returns a RefreshToken if valid. Expired and malformed tokens
+// are reported with distinct messages
+func (s *TokenService) ValidateRefreshToken(tokenString string) (*model.RefreshToken, error) {
+	claims, err := validateRefreshToken(tokenString, s.RefreshSecret)
+
+	if err != nil {
+		slog.Info("Unable to validate or parse refreshToken", "err", err)
+
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, apperrors.NewAuthorization("Refresh token has expired")
+		}
+
+		return nil, apperrors.NewAuthorization("Refresh token is malformed or invalid")
+	}
+
+	// registered claims store the ID as a string, model.RefreshToken
+	// makes it clear the ID is a UUID
+	tokenUUID, err := uuid.Parse(claims.ID)
+
+	if err != nil {
+		slog.Warn("Claims ID could not be parsed as UUID", "claims_id", claims.ID, "err", err)
+		return nil, apperrors.NewAuthorization("Refresh token is malformed or invalid")
+	}
+
+	return &model.RefreshToken{
+		SS:  tokenString,
+		ID:  tokenUUID,
+		UID: claims.UID,
+	}, nil
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+func TestNewPairFromUser(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pubKey := &privKey.PublicKey
+	secret := "anotsorandomtestsecret"
+
+	var idExp int64 = 15 * 60
+	var refreshExp int64 = 3 * 24 * 3600
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository:       mockTokenRepository,
+		PrivKey:               privKey,
+		PubKey:                pubKey,
+		RefreshSecret:         secret,
+		IDExpirationSecs:      idExp,
+		RefreshExpirationSecs: refreshExp,
+	})
+
+	uid, _ := uuid.NewRandom()
+
+	u := &model.User{
+		UID:      uid,
+		Email:    "vuluu040320@gmail.com",
+		Password: "blarghedymcblarghface",
+	}
+
+	// Setup mock call responses in setup before t.Run statements
+	uidErrorCase, _ := uuid.NewRandom()
+	uErrorCase := &model.User{
+		UID:      uidErrorCase,
+		Email:    "failure@failure.com",
+		Password: "blarghedymcblarghface",
+	}
+
+	uidReuseCase, _ := uuid.NewRandom()
+	uReuseCase := &model.User{
+		UID:      uidReuseCase,
+		Email:    "reuse@reuse.com",
+		Password: "blarghedymcblarghface",
+	}
+
+	prevID := "a_previous_tokenID"
+	stolenID := "a_stolen_tokenID"
+
+	setSuccessArguments := mock.Arguments{
+		mock.Anything,
+		u.UID.String(),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("time.Duration"),
+	}
+
+	setErrorArguments := mock.Arguments{
+		mock.Anything,
+		uidErrorCase.String(),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("time.Duration"),
+	}
+
+	deleteWithPrevIDArguments := mock.Arguments{
+		mock.Anything,
+		u.UID.String(),
+		prevID,
+	}
+
+	deleteStolenIDArguments := mock.Arguments{
+		mock.Anything,
+		uReuseCase.UID.String(),
+		stolenID,
+	}
+
+	// mock call argument/responses
+	mockTokenRepository.On("SetRefreshToken", setSuccessArguments...).Return(nil)
+	mockTokenRepository.On("SetRefreshToken", setErrorArguments...).Return(fmt.Errorf("Error setting refresh token"))
+	mockTokenRepository.On("DeleteRefreshToken", deleteWithPrevIDArguments...).Return(nil)
+	mockTokenRepository.On("DeleteRefreshToken", deleteStolenIDArguments...).Return(apperrors.NewAuthorization("Invalid refresh token"))
+	mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, uReuseCase.UID.String()).Return(nil)
+
+	t.Run("Returns a token pair with proper values", func(t *testing.T) {
+		ctx := context.TODO()
+
+		tokenPair, err := tokenService.NewPairFromUser(ctx, u, prevID)
+		assert.NoError(t, err)
+
+		// SetRefreshToken should be called with setSuccessArguments
+		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
+		// DeleteRefreshToken should be called since prevID is not ""
+		mockTokenRepository.AssertCalled(t, "DeleteRefreshToken", deleteWithPrevIDArguments...)
+
+		var s string
+		assert.IsType(t, s, tokenPair.TokenID)
+
+		idTokenClaims := &idTokenCustomClaims{}
+
+		_, err = jwt.ParseWithClaims(tokenPair.TokenID, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
+			return pubKey, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name}))
+
+		assert.NoError(t, err)
+
+		// assert claims on idToken
+		expectedClaims := []interface{}{
+			u.UID,
+			u.Email,
+			u.Name,
+			u.ImageUrl,
+			u.Website,
+		}
+		actualIDClaims := []interface{}{
+			idTokenClaims.User.UID,
+			idTokenClaims.User.Email,
+			idTokenClaims.User.Name,
+			idTokenClaims.User.ImageUrl,
+			idTokenClaims.User.Website,
+		}
+
+		assert.ElementsMatch(t, expectedClaims, actualIDClaims)
+		assert.Empty(t, idTokenClaims.User.Password) // password should never be encoded to json
+
+		expiresAt := idTokenClaims.ExpiresAt.Time
+		expectedExpiresAt := time.Now().Add(time.Duration(idExp) * time.Second)
+		assert.WithinDuration(t, expectedExpiresAt, expiresAt, 5*time.Second)
+
+		refreshTokenClaims := &refreshTokenCustomClaims{}
+
+		_, err = jwt.ParseWithClaims(tokenPair.RefreshToken, refreshTokenClaims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+
+		assert.NoError(t, err)
+
+		assert.Equal(t, u.UID, refreshTokenClaims.UID)
+		assert.NotEmpty(t, refreshTokenClaims.ID)
+
+		expiresAt = refreshTokenClaims.ExpiresAt.Time
+		expectedExpiresAt = time.Now().Add(time.Duration(refreshExp) * time.Second)
+		assert.WithinDuration(t, expectedExpiresAt, expiresAt, 5*time.Second)
+	})
+
+	t.Run("Refresh tokens have unique IDs", func(t *testing.T) {
+		ctx := context.TODO()
+
+		first, err := tokenService.NewPairFromUser(ctx, u, "")
+		assert.NoError(t, err)
+
+		second, err := tokenService.NewPairFromUser(ctx, u, "")
+		assert.NoError(t, err)
+
+		firstClaims := &refreshTokenCustomClaims{}
+		_, _, err = jwt.NewParser().ParseUnverified(first.RefreshToken, firstClaims)
+		assert.NoError(t, err)
+
+		secondClaims := &refreshTokenCustomClaims{}
+		_, _, err = jwt.NewParser().ParseUnverified(second.RefreshToken, secondClaims)
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, firstClaims.ID, secondClaims.ID)
+	})
+	t.Run("Error setting refresh token", func(t *testing.T) {
+		ctx := context.TODO()
+		_, err := tokenService.NewPairFromUser(ctx, uErrorCase, "")
+		assert.Error(t, err) // should return an error
+
+		// SetRefreshToken should be called with setErrorArguments
+		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setErrorArguments...)
+		// DeleteRefreshToken should not be since SetRefreshToken causes method to return
+		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken", mock.Anything, uidErrorCase.String(), mock.Anything)
+	})
+
+	t.Run("Reused refresh token revokes all user tokens", func(t *testing.T) {
+		ctx := context.TODO()
+		tokenPair, err := tokenService.NewPairFromUser(ctx, uReuseCase, stolenID)
+
+		assert.Nil(t, tokenPair)
+		assert.Equal(t, apperrors.NewAuthorization("Refresh token has been revoked"), err)
+
+		mockTokenRepository.AssertCalled(t, "DeleteRefreshToken", deleteStolenIDArguments...)
+		mockTokenRepository.AssertCalled(t, "DeleteUserRefreshTokens", mock.Anything, uReuseCase.UID.String())
+		// no new token should be issued for a reused token
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, uReuseCase.UID.String(), mock.Anything, mock.Anything)
+	})
+}
+
+func TestValidateIDToken(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pubKey := &privKey.PublicKey
+
+	// instantiate a common token service to be used by all tests
+	tokenService := NewTokenService(&TSConfig{
+		PrivKey:          privKey,
+		PubKey:           pubKey,
+		IDExpirationSecs: 15 * 60,
+	})
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "vuluu040320@gmail.com",
+		Name:  "Vũ Lưu",
+	}
+
+	t.Run("Valid token", func(t *testing.T) {
+		ss, _ := generateIDToken(u, privKey, 15*60)
+
+		uFromToken, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+
+		assert.ElementsMatch(
+			t,
+			[]interface{}{u.Email, u.Name, u.UID, u.Website, u.ImageUrl},
+			[]interface{}{uFromToken.Email, uFromToken.Name, uFromToken.UID, uFromToken.Website, uFromToken.ImageUrl},
+		)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		ss, _ := generateIDToken(u, privKey, -1) // expires one second ago
+
+		expectedErr := apperrors.NewAuthorization("Unable to verify user from idToken")
+
+		_, err := tokenService.ValidateIDToken(ss)
+		assert.EqualError(t, err, expectedErr.Message)
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		ss, _ := generateIDToken(u, otherKey, 15*60)
+
+		expectedErr := apperrors.NewAuthorization("Unable to verify user from idToken")
+
+		_, err = tokenService.ValidateIDToken(ss)
+		assert.EqualError(t, err, expectedErr.Message)
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		expectedErr := apperrors.NewAuthorization("Unable to verify user from idToken")
+
+		_, err := tokenService.ValidateIDToken("not.a.jwt")
+		assert.EqualError(t, err, expectedErr.Message)
+	})
+}
+
+func TestValidateRefreshToken(t *testing.T) {
+	var refreshExp int64 = 3 * 24 * 3600
+	secret := "anotsorandomtestsecret"
+
+	tokenService := NewTokenService(&TSConfig{
+		RefreshSecret:         secret,
+		RefreshExpirationSecs: refreshExp,
+	})
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Valid token", func(t *testing.T) {
+		testRefreshToken, _ := generateRefreshToken(uid, secret, refreshExp)
+
+		validatedRefreshToken, err := tokenService.ValidateRefreshToken(testRefreshToken.SS)
+		assert.NoError(t, err)
+
+		assert.Equal(t, uid, validatedRefreshToken.UID)
+		assert.Equal(t, testRefreshToken.SS, validatedRefreshToken.SS)
+		assert.Equal(t, testRefreshToken.ID, validatedRefreshToken.ID)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		testRefreshToken, _ := generateRefreshToken(uid, secret, -1)
+
+		_, err := tokenService.ValidateRefreshToken(testRefreshToken.SS)
+		assert.Equal(t, apperrors.NewAuthorization("Refresh token has expired"), err)
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		testRefreshToken, _ := generateRefreshToken(uid, "adifferentsecret", refreshExp)
+
+		_, err := tokenService.ValidateRefreshToken(testRefreshToken.SS)
+		assert.Equal(t, apperrors.NewAuthorization("Refresh token is malformed or invalid"), err)
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		_, err := tokenService.ValidateRefreshToken("not.a.jwt")
+		assert.Equal(t, apperrors.NewAuthorization("Refresh token is malformed or invalid"), err)
+	})
+
+	t.Run("Other tokens signed with the same secret", func(t *testing.T) {
+		challengeToken, err := generateChallengeToken(uid, secret, 300)
+		require.NoError(t, err)
+
+		_, err = tokenService.ValidateRefreshToken(challengeToken)
+		assert.Equal(t, apperrors.NewAuthorization("Refresh token is malformed or invalid"), err)
+
+		verificationToken, err := generateVerificationToken(&model.User{UID: uid}, secret, 300)
+		require.NoError(t, err)
+
+		_, err = tokenService.ValidateRefreshToken(verificationToken.SS)
+		assert.Equal(t, apperrors.NewAuthorization("Refresh token is malformed or invalid"), err)
+	})
+}
+
+func TestSignout(t *testing.T) {
+	mockTokenRepository := new(mocks.MockTokenRepository)
+
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository: mockTokenRepository,
+	})
+
+	t.Run("No error", func(t *testing.T) {
+		uidSuccess, _ := uuid.NewRandom()
+
+		mockTokenRepository.
+			On("DeleteUserRefreshTokens", mock.Anything, uidSuccess.String()).
+			Return(nil)
+
+		ctx := context.Background()
+		err := tokenService.Signout(ctx, uidSuccess)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		uidError, _ := uuid.NewRandom()
+
+		mockTokenRepository.
+			On("DeleteUserRefreshTokens", mock.Anything, uidError.String()).
+			Return(apperrors.NewInternal())
+
+		ctx := context.Background()
+		err := tokenService.Signout(ctx, uidError)
+
+		assert.Error(t, err)
+
+		apperr, ok := err.(*apperrors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.Internal, apperr.Type)
+	})
+}
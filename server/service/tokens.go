@@ -0,0 +1,287 @@
+package service
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+)
+
+// idTokenCustomClaims holds structure of jwt claims of idToken
+type idTokenCustomClaims struct {
+	User *model.User `json:"user"`
+	jwt.RegisteredClaims
+}
+
+// generateIDToken generates an RS256 signed idToken embedding the user
+func generateIDToken(u *model.User, key *rsa.PrivateKey, exp int64) (string, error) {
+	unixTime := time.Now()
+	tokenExp := unixTime.Add(time.Duration(exp) * time.Second)
+
+	// never embed the password hash in a token handed to clients
+	claimsUser := *u
+	claimsUser.Password = ""
+
+	claims := idTokenCustomClaims{
+		User: &claimsUser,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(unixTime),
+			ExpiresAt: jwt.NewNumericDate(tokenExp),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	ss, err := token.SignedString(key)
+
+	if err != nil {
+		slog.Error("Failed to sign id token string", "err", err)
+		return "", err
+	}
+
+	return ss, nil
+}
+
+// validateIDToken returns the token's claims if the token is valid
+func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClaims, error) {
+	claims := &idTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name}))
+
+	// For now we'll just return the error and handle logging in service level
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("ID token is invalid")
+	}
+
+	if claims.User == nil {
+		return nil, fmt.Errorf("ID token valid but couldn't parse claims")
+	}
+
+	return claims, nil
+}
+
+// refreshTokenData holds the actual signed jwt string along with the ID
+// We return the id so it can be used without re-parsing the JWT from signed string
+type refreshTokenData struct {
+	SS        string
+	ID        uuid.UUID
+	ExpiresIn time.Duration
+}
+
+// refreshAudience tells refresh tokens apart from other HS256 tokens,
+// should their secrets ever be the same
+const refreshAudience = "refresh"
+
+// refreshTokenCustomClaims holds the payload of a refresh token
+// This can be used to extract user id for subsequent
+// application operations (IE, fetch user in Redis)
+type refreshTokenCustomClaims struct {
+	UID uuid.UUID `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+// generateRefreshToken creates an HS256 signed refresh token with its own ID
+func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenData, error) {
+	currentTime := time.Now()
+	tokenExp := currentTime.Add(time.Duration(exp) * time.Second)
+	tokenID, err := uuid.NewRandom()
+
+	if err != nil {
+		slog.Error("Failed to generate refresh token ID", "err", err)
+		return nil, err
+	}
+
+	claims := refreshTokenCustomClaims{
+		UID: uid,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(currentTime),
+			ExpiresAt: jwt.NewNumericDate(tokenExp),
+			ID:        tokenID.String(),
+			Audience:  jwt.ClaimStrings{refreshAudience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString([]byte(key))
+
+	if err != nil {
+		slog.Error("Failed to sign refresh token string", "err", err)
+		return nil, err
+	}
+
+	return &refreshTokenData{
+		SS:        ss,
+		ID:        tokenID,
+		ExpiresIn: tokenExp.Sub(currentTime),
+	}, nil
+}
+
+// validateRefreshToken uses the secret key to validate a refresh token
+func validateRefreshToken(tokenString string, key string) (*refreshTokenCustomClaims, error) {
+	claims := &refreshTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(key), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithAudience(refreshAudience))
+
+	// For now we'll just return the error and handle logging in service level
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("refresh token is invalid")
+	}
+
+	return claims, nil
+}
+
+// verificationAudience tells verification tokens apart from other
+// HS256 tokens
+const verificationAudience = "email-verification"
+
+// verificationTokenData holds the signed jwt string along with its ID
+type verificationTokenData struct {
+	SS        string
+	ID        uuid.UUID
+	ExpiresIn time.Duration
+}
+
+// verificationTokenCustomClaims holds the payload of an email
+// verification token, valid only for the email it was sent to
+type verificationTokenCustomClaims struct {
+	UID   uuid.UUID `json:"uid"`
+	Email string    `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// generateVerificationToken creates an HS256 signed email verification
+// token with its own ID
+func generateVerificationToken(u *model.User, key string, exp int64) (*verificationTokenData, error) {
+	currentTime := time.Now()
+	tokenExp := currentTime.Add(time.Duration(exp) * time.Second)
+	tokenID, err := uuid.NewRandom()
+
+	if err != nil {
+		slog.Error("Failed to generate verification token ID", "err", err)
+		return nil, err
+	}
+
+	claims := verificationTokenCustomClaims{
+		UID:   u.UID,
+		Email: u.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(currentTime),
+			ExpiresAt: jwt.NewNumericDate(tokenExp),
+			ID:        tokenID.String(),
+			Audience:  jwt.ClaimStrings{verificationAudience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString([]byte(key))
+
+	if err != nil {
+		slog.Error("Failed to sign verification token string", "err", err)
+		return nil, err
+	}
+
+	return &verificationTokenData{
+		SS:        ss,
+		ID:        tokenID,
+		ExpiresIn: tokenExp.Sub(currentTime),
+	}, nil
+}
+
+// validateVerificationToken uses the secret key to validate an email
+// verification token
+func validateVerificationToken(tokenString string, key string) (*verificationTokenCustomClaims, error) {
+	claims := &verificationTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(key), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithAudience(verificationAudience))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("verification token is invalid")
+	}
+
+	return claims, nil
+}
+
+// challengeAudience tells two-factor challenge tokens apart from other
+// HS256 tokens
+const challengeAudience = "sign-in-2fa"
+
+// challengeTokenCustomClaims holds the payload of a two-factor
+// challenge token, handed out after a correct password
+type challengeTokenCustomClaims struct {
+	UID uuid.UUID `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+// generateChallengeToken creates an HS256 signed two-factor challenge
+// token for the user
+func generateChallengeToken(uid uuid.UUID, key string, exp int64) (string, error) {
+	currentTime := time.Now()
+	tokenExp := currentTime.Add(time.Duration(exp) * time.Second)
+	tokenID, err := uuid.NewRandom()
+
+	if err != nil {
+		slog.Error("Failed to generate challenge token ID", "err", err)
+		return "", err
+	}
+
+	claims := challengeTokenCustomClaims{
+		UID: uid,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(currentTime),
+			ExpiresAt: jwt.NewNumericDate(tokenExp),
+			ID:        tokenID.String(),
+			Audience:  jwt.ClaimStrings{challengeAudience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString([]byte(key))
+
+	if err != nil {
+		slog.Error("Failed to sign challenge token string", "err", err)
+		return "", err
+	}
+
+	return ss, nil
+}
+
+// validateChallengeToken uses the secret key to validate a two-factor
+// challenge token
+func validateChallengeToken(tokenString string, key string) (*challengeTokenCustomClaims, error) {
+	claims := &challengeTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(key), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithAudience(challengeAudience))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("challenge token is invalid")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	// test vectors of https://www.rfc-editor.org/rfc/rfc6238#appendix-B
+	// for SHA1, truncated to six digits
+	rfcSecret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("RFC 6238 test vectors", func(t *testing.T) {
+		for unix, code := range map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1111111111: "050471",
+			1234567890: "005924",
+			2000000000: "279037",
+		} {
+			step, ok := validateTOTP(rfcSecret, code, time.Unix(unix, 0))
+
+			assert.True(t, ok, unix)
+			assert.Equal(t, unix/30, step)
+		}
+	})
+
+	t.Run("Accepts one step of skew", func(t *testing.T) {
+		now := time.Unix(1111111109, 0)
+
+		_, ok := validateTOTP(rfcSecret, "081804", now.Add(totpPeriod))
+		assert.True(t, ok)
+
+		_, ok = validateTOTP(rfcSecret, "081804", now.Add(-totpPeriod))
+		assert.True(t, ok)
+
+		_, ok = validateTOTP(rfcSecret, "081804", now.Add(2*totpPeriod))
+		assert.False(t, ok)
+	})
+
+	t.Run("Rejects wrong codes and secrets", func(t *testing.T) {
+		now := time.Unix(1111111109, 0)
+
+		for _, code := range []string{"081805", "81804", "0818040", ""} {
+			_, ok := validateTOTP(rfcSecret, code, now)
+			assert.False(t, ok, code)
+		}
+
+		_, ok := validateTOTP("not base32!", "081804", now)
+		assert.False(t, ok)
+	})
+
+	t.Run("New secrets", func(t *testing.T) {
+		secret, err := generateTOTPSecret()
+		require.NoError(t, err)
+
+		key, err := totpEncoding.DecodeString(secret)
+		assert.NoError(t, err)
+		assert.Len(t, key, totpSecretBytes)
+
+		now := time.Now()
+		step, ok := validateTOTP(secret, totpCode(key, totpStep(now)), now)
+		assert.True(t, ok)
+		assert.Equal(t, totpStep(now), step)
+	})
+
+	t.Run("otpauth URI", func(t *testing.T) {
+		uri, err := url.Parse(totpURI("Remember", "vuluu040320@gmail.com", "JBSWY3DPEHPK3PXP"))
+		require.NoError(t, err)
+
+		assert.Equal(t, "otpauth", uri.Scheme)
+		assert.Equal(t, "totp", uri.Host)
+		assert.Equal(t, "/Remember:vuluu040320@gmail.com", uri.Path)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+		assert.Equal(t, "Remember", uri.Query().Get("issuer"))
+		assert.Equal(t, "6", uri.Query().Get("digits"))
+		assert.Equal(t, "30", uri.Query().Get("period"))
+	})
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	require.NoError(t, err)
+
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Len(t, hashes, recoveryCodeCount)
+
+	seen := map[string]bool{}
+
+	for i, code := range codes {
+		assert.Len(t, code, recoveryCodeLen+1)
+		assert.False(t, isTOTPCode(code))
+		assert.False(t, seen[code])
+		seen[code] = true
+
+		assert.Equal(t, hashRecoveryCode(code), hashes[i])
+		assert.NotContains(t, hashes[i], code)
+
+		// as users might type it
+		typed := strings.ToLower(strings.ReplaceAll(code, "-", " "))
+		assert.Equal(t, hashes[i], hashRecoveryCode(typed))
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/metrics"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// invalidTwoFactorCode covers wrong TOTP and recovery codes alike
+const invalidTwoFactorCode = "Invalid two-factor code"
+
+// invalidChallenge covers bad signatures and expired challenge tokens
+const invalidChallenge = "Two-factor challenge is invalid or has expired"
+
+// TwoFactorService enrolls TOTP authenticators and checks their codes
+// as a second step of signing in. Wrong codes count towards the same
+// Lockout as wrong passwords
+type TwoFactorService struct {
+	UserRepository model.UserRepository
+	Lockout        LockoutPolicy
+	// Issuer names the account in authenticator apps
+	Issuer                  string
+	ChallengeSecret         string
+	ChallengeExpirationSecs int64
+}
+
+type TFSConfig struct {
+	UserRepository          model.UserRepository
+	Lockout                 LockoutPolicy
+	Issuer                  string
+	ChallengeSecret         string
+	ChallengeExpirationSecs int64
+}
+
+func NewTwoFactorService(c *TFSConfig) model.TwoFactorService {
+	return &TwoFactorService{
+		UserRepository:          c.UserRepository,
+		Lockout:                 c.Lockout,
+		Issuer:                  c.Issuer,
+		ChallengeSecret:         c.ChallengeSecret,
+		ChallengeExpirationSecs: c.ChallengeExpirationSecs,
+	}
+}
+
+func (s *TwoFactorService) Enroll(ctx context.Context, uid uuid.UUID) (string, error) {
+	u, err := s.UserRepository.FindById(ctx, uid)
+
+	if err != nil {
+		return "", err
+	}
+
+	if u.TOTPEnabled {
+		return "", apperrors.NewBadRequest("two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to generate TOTP secret", "uid", uid, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.SetTOTPSecret(ctx, uid, secret); err != nil {
+		// enabled since it was fetched
+		if apperrors.Status(err) == http.StatusNotFound {
+			return "", apperrors.NewBadRequest("two-factor authentication is already enabled")
+		}
+
+		return "", err
+	}
+
+	return totpURI(s.Issuer, u.Email, secret), nil
+}
+
+func (s *TwoFactorService) Confirm(ctx context.Context, uid uuid.UUID, code string) ([]string, error) {
+	u, err := s.UserRepository.FindById(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.TOTPEnabled {
+		return nil, apperrors.NewBadRequest("two-factor authentication is already enabled")
+	}
+
+	if u.TOTPSecret == "" {
+		return nil, apperrors.NewBadRequest("two-factor authentication has not been enrolled")
+	}
+
+	// only codes from the authenticator confirm it works
+	if err := s.checkTOTP(ctx, u, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to generate recovery codes", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.EnableTOTP(ctx, uid, hashes); err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, apperrors.NewBadRequest("two-factor authentication has not been enrolled")
+		}
+
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *TwoFactorService) Disable(ctx context.Context, uid uuid.UUID, code string) error {
+	u, err := s.UserRepository.FindById(ctx, uid)
+
+	if err != nil {
+		return err
+	}
+
+	if !u.TOTPEnabled {
+		return apperrors.NewBadRequest("two-factor authentication is not enabled")
+	}
+
+	if u.IsLocked(time.Now()) {
+		return apperrors.NewAuthorization(accountLocked)
+	}
+
+	if err := s.checkCode(ctx, u, code); err != nil {
+		return s.failCode(ctx, u, err)
+	}
+
+	return s.UserRepository.DisableTOTP(ctx, uid)
+}
+
+func (s *TwoFactorService) NewChallenge(ctx context.Context, u *model.User) (string, error) {
+	token, err := generateChallengeToken(u.UID, s.ChallengeSecret, s.ChallengeExpirationSecs)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Error generating challenge token", "uid", u.UID, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return token, nil
+}
+
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, challengeToken string, code string) (*model.User, error) {
+	claims, err := validateChallengeToken(challengeToken, s.ChallengeSecret)
+
+	if err != nil {
+		logging.FromContext(ctx).Info("Unable to validate or parse challenge token", "err", err)
+		return nil, apperrors.NewAuthorization(invalidChallenge)
+	}
+
+	u, err := s.UserRepository.FindById(ctx, claims.UID)
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, apperrors.NewAuthorization(invalidChallenge)
+		}
+
+		metrics.SignInFailures.WithLabelValues(metrics.SignInError).Inc()
+		return nil, err
+	}
+
+	// disabled since the challenge was made, signing in again skips it
+	if !u.TOTPEnabled {
+		return nil, apperrors.NewAuthorization(invalidChallenge)
+	}
+
+	if u.IsLocked(time.Now()) {
+		metrics.SignInFailures.WithLabelValues(metrics.SignInLocked).Inc()
+		return nil, apperrors.NewAuthorization(accountLocked)
+	}
+
+	if err := s.checkCode(ctx, u, code); err != nil {
+		if apperrors.Status(err) != http.StatusUnauthorized {
+			metrics.SignInFailures.WithLabelValues(metrics.SignInError).Inc()
+			return nil, err
+		}
+
+		metrics.SignInFailures.WithLabelValues(metrics.SignInWrongCode).Inc()
+		return nil, s.failCode(ctx, u, err)
+	}
+
+	if u.FailedSignIns > 0 || u.LockedUntil != nil {
+		if err := s.UserRepository.ResetFailedSignIns(ctx, u.UID); err != nil {
+			return nil, err
+		}
+
+		u.FailedSignIns = 0
+		u.LockedUntil = nil
+	}
+
+	return u, nil
+}
+
+// checkCode accepts a TOTP code or an unused recovery code of u
+func (s *TwoFactorService) checkCode(ctx context.Context, u *model.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if isTOTPCode(code) {
+		return s.checkTOTP(ctx, u, code)
+	}
+
+	if err := s.UserRepository.ConsumeRecoveryCode(ctx, u.UID, hashRecoveryCode(code)); err != nil {
+		if apperrors.Status(err) == http.StatusUnauthorized {
+			return apperrors.NewAuthorization(invalidTwoFactorCode)
+		}
+
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Recovery code used", "uid", u.UID, "remaining", len(u.RecoveryCodes)-1)
+
+	return nil
+}
+
+// checkTOTP accepts a code of the authenticator of u, once
+func (s *TwoFactorService) checkTOTP(ctx context.Context, u *model.User, code string) error {
+	step, ok := validateTOTP(u.TOTPSecret, strings.TrimSpace(code), time.Now())
+
+	if !ok {
+		return apperrors.NewAuthorization(invalidTwoFactorCode)
+	}
+
+	return s.UserRepository.UseTOTPStep(ctx, u.UID, step)
+}
+
+// failCode counts a rejected code of u towards the Lockout, returning
+// the error to respond with
+func (s *TwoFactorService) failCode(ctx context.Context, u *model.User, codeErr error) error {
+	if apperrors.Status(codeErr) != http.StatusUnauthorized {
+		return codeErr
+	}
+
+	locked, err := s.Lockout.recordFailure(ctx, s.UserRepository, u)
+
+	if err != nil {
+		return err
+	}
+
+	if locked {
+		return apperrors.NewAuthorization(accountLocked)
+	}
+
+	return codeErr
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+// currentTOTPCode is the code an authenticator of secret shows now
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+
+	return totpCode(key, totpStep(time.Now()))
+}
+
+func TestTwoFactorService(t *testing.T) {
+	secret := "anotsorandomchallengesecret"
+	totpSecret := "JBSWY3DPEHPK3PXP"
+	uid, _ := uuid.NewRandom()
+
+	policy := LockoutPolicy{
+		Threshold:    3,
+		BaseDuration: time.Minute,
+		MaxDuration:  time.Hour,
+	}
+
+	enrolled := func() *model.User {
+		return &model.User{
+			UID:           uid,
+			Email:         "vuluu040320@gmail.com",
+			TOTPSecret:    totpSecret,
+			TOTPEnabled:   true,
+			RecoveryCodes: []string{hashRecoveryCode("ABCDE-FGHIJ")},
+		}
+	}
+
+	t.Run("Enroll and confirm", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		var stored string
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, Email: "vuluu040320@gmail.com"}, nil).Once()
+		mockUserRepository.On("SetTOTPSecret", mock.Anything, uid, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				stored = args.String(2)
+			}).Return(nil)
+
+		uri, err := tfs.Enroll(context.TODO(), uid)
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(uri)
+		require.NoError(t, err)
+		assert.Equal(t, stored, parsed.Query().Get("secret"))
+
+		var hashes []string
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, TOTPSecret: stored}, nil).Once()
+		mockUserRepository.On("UseTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(nil)
+		mockUserRepository.On("EnableTOTP", mock.Anything, uid, mock.AnythingOfType("[]string")).
+			Run(func(args mock.Arguments) {
+				hashes = args.Get(2).([]string)
+			}).Return(nil)
+
+		codes, err := tfs.Confirm(context.TODO(), uid, currentTOTPCode(t, stored))
+		require.NoError(t, err)
+
+		assert.Len(t, codes, recoveryCodeCount)
+		require.Len(t, hashes, recoveryCodeCount)
+		assert.Equal(t, hashRecoveryCode(codes[0]), hashes[0])
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Enroll when already enabled", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(enrolled(), nil)
+
+		_, err := tfs.Enroll(context.TODO(), uid)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "SetTOTPSecret", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Confirm with a wrong code", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, TOTPSecret: totpSecret}, nil)
+
+		_, err := tfs.Confirm(context.TODO(), uid, "000000")
+		assert.EqualError(t, err, invalidTwoFactorCode)
+		mockUserRepository.AssertNotCalled(t, "EnableTOTP", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Challenge with a TOTP code", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		challenge, err := tfs.NewChallenge(context.TODO(), enrolled())
+		require.NoError(t, err)
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(enrolled(), nil)
+		mockUserRepository.On("UseTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(nil).Once()
+
+		u, err := tfs.VerifyChallenge(context.TODO(), challenge, currentTOTPCode(t, totpSecret))
+		require.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+
+		// the same code can't be replayed
+		mockUserRepository.On("UseTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(apperrors.NewAuthorization("Two-factor code has already been used"))
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(1, nil)
+
+		_, err = tfs.VerifyChallenge(context.TODO(), challenge, currentTOTPCode(t, totpSecret))
+		assert.EqualError(t, err, "Two-factor code has already been used")
+	})
+
+	t.Run("Challenge with a recovery code", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		challenge, err := tfs.NewChallenge(context.TODO(), enrolled())
+		require.NoError(t, err)
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(enrolled(), nil)
+		mockUserRepository.On("ConsumeRecoveryCode", mock.Anything, uid, hashRecoveryCode("ABCDE-FGHIJ")).Return(nil)
+
+		u, err := tfs.VerifyChallenge(context.TODO(), challenge, "abcde-fghij")
+		require.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		mockUserRepository.AssertNotCalled(t, "UseTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Wrong codes lock the account", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		challenge, err := tfs.NewChallenge(context.TODO(), enrolled())
+		require.NoError(t, err)
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(enrolled(), nil)
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(2, nil).Once()
+
+		_, err = tfs.VerifyChallenge(context.TODO(), challenge, "000000")
+		assert.EqualError(t, err, invalidTwoFactorCode)
+
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(3, nil).Once()
+		mockUserRepository.On("LockUntil", mock.Anything, uid, mock.AnythingOfType("time.Time")).Return(nil)
+
+		_, err = tfs.VerifyChallenge(context.TODO(), challenge, "000000")
+		assert.EqualError(t, err, accountLocked)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Rejects other tokens as challenges", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		forged, err := generateChallengeToken(uid, "anothersecret", 300)
+		require.NoError(t, err)
+
+		_, err = tfs.VerifyChallenge(context.TODO(), forged, "000000")
+		assert.EqualError(t, err, invalidChallenge)
+
+		// verification tokens are HS256 signed too, but for another audience
+		verification, err := generateVerificationToken(enrolled(), secret, 300)
+		require.NoError(t, err)
+
+		_, err = tfs.VerifyChallenge(context.TODO(), verification.SS, "000000")
+		assert.EqualError(t, err, invalidChallenge)
+
+		expired, err := generateChallengeToken(uid, secret, -1)
+		require.NoError(t, err)
+
+		_, err = tfs.VerifyChallenge(context.TODO(), expired, "000000")
+		assert.EqualError(t, err, invalidChallenge)
+
+		mockUserRepository.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(enrolled(), nil)
+		mockUserRepository.On("UseTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(nil)
+		mockUserRepository.On("DisableTOTP", mock.Anything, uid).Return(nil)
+
+		assert.NoError(t, tfs.Disable(context.TODO(), uid, currentTOTPCode(t, totpSecret)))
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Disable with a wrong code", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		tfs := NewTwoFactorService(&TFSConfig{
+			UserRepository:          mockUserRepository,
+			Lockout:                 policy,
+			Issuer:                  "Remember",
+			ChallengeSecret:         secret,
+			ChallengeExpirationSecs: 300,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(enrolled(), nil)
+		mockUserRepository.On("ConsumeRecoveryCode", mock.Anything, uid, hashRecoveryCode("WRONG-CODE")).Return(apperrors.NewAuthorization("Invalid recovery code"))
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(1, nil)
+
+		err := tfs.Disable(context.TODO(), uid, "WRONG-CODE")
+		assert.EqualError(t, err, invalidTwoFactorCode)
+		mockUserRepository.AssertNotCalled(t, "DisableTOTP", mock.Anything, mock.Anything)
+	})
+}
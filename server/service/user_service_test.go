@@ -1,15 +1,24 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"mime/multipart"
+	"net/textproto"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/metrics"
 	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
 	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestGet(t *testing.T) {
@@ -59,3 +68,886 @@ func TestGet(t *testing.T) {
 	})
 
 }
+
+func TestSignUp(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			Email:    "vuluu040320@gmail.com",
+			Password: "SuperKeyPass123",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		// we can use Run method to modify the user when the Create method is called
+		mockUserRepository.
+			On("Create", mock.Anything, mockUser).
+			Run(func(args mock.Arguments) {
+				userArg := args.Get(1).(*model.User)
+				userArg.UID = uid
+			}).Return(nil)
+
+		ctx := context.TODO()
+
+		err := us.SignUp(ctx, mockUser)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, mockUser.UID)
+		assert.NotEqual(t, "SuperKeyPass123", mockUser.Password)
+
+		match, err := comparePasswords(mockUser.Password, "SuperKeyPass123", HashParams{})
+
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockUser := &model.User{
+			Email:    "vuluu040320@gmail.com",
+			Password: "SuperKeyPass123",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockErr := apperrors.NewConflict("email", mockUser.Email)
+
+		mockUserRepository.On("Create", mock.Anything, mockUser).Return(mockErr)
+
+		ctx := context.TODO()
+
+		err := us.SignUp(ctx, mockUser)
+
+		assert.EqualError(t, err, mockErr.Error())
+		assert.Equal(t, mockErr.Status(), apperrors.Status(err))
+
+		mockUserRepository.AssertExpectations(t)
+	})
+}
+
+func TestSignIn(t *testing.T) {
+	email := "vuluu040320@gmail.com"
+	validPW := "SuperKeyPass123"
+	hashedValidPW, _ := hashPassword(validPW, HashParams{})
+	invalidPW := "WrongPass123"
+
+	mockUserRepository := new(mocks.MockUserRepository)
+
+	us := NewUserService(&USConfig{
+		UserRepository: mockUserRepository,
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			Email:    email,
+			Password: validPW,
+		}
+
+		mockUserResp := &model.User{
+			UID:      uid,
+			Email:    email,
+			Password: hashedValidPW,
+		}
+
+		mockArgs := mock.Arguments{
+			mock.Anything,
+			email,
+		}
+
+		mockUserRepository.
+			On("FindByEmail", mockArgs...).Return(mockUserResp, nil).Once()
+
+		ctx := context.TODO()
+		err := us.SignIn(ctx, mockUser)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, mockUser.UID)
+		mockUserRepository.AssertCalled(t, "FindByEmail", mockArgs...)
+	})
+
+	t.Run("Invalid email/password combination", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			Email:    email,
+			Password: invalidPW,
+		}
+
+		mockUserResp := &model.User{
+			UID:      uid,
+			Email:    email,
+			Password: hashedValidPW,
+		}
+
+		mockArgs := mock.Arguments{
+			mock.Anything,
+			email,
+		}
+
+		mockUserRepository.
+			On("FindByEmail", mockArgs...).Return(mockUserResp, nil).Once()
+
+		failures := testutil.ToFloat64(metrics.SignInFailures.WithLabelValues(metrics.SignInWrongPassword))
+
+		ctx := context.TODO()
+		err := us.SignIn(ctx, mockUser)
+
+		assert.Error(t, err)
+		assert.EqualError(t, err, "Invalid email and password combination")
+		assert.Equal(t, uuid.Nil, mockUser.UID)
+		assert.Equal(t, failures+1, testutil.ToFloat64(metrics.SignInFailures.WithLabelValues(metrics.SignInWrongPassword)))
+		mockUserRepository.AssertCalled(t, "FindByEmail", mockArgs...)
+	})
+
+	t.Run("Unknown email gives the same error as a wrong password", func(t *testing.T) {
+		unknownEmail := "nobody@remember.test"
+
+		mockUser := &model.User{
+			Email:    unknownEmail,
+			Password: validPW,
+		}
+
+		mockUserRepository.
+			On("FindByEmail", mock.Anything, unknownEmail).Return(nil, apperrors.NewNotFound("email", unknownEmail)).Once()
+
+		failures := testutil.ToFloat64(metrics.SignInFailures.WithLabelValues(metrics.SignInUnknownEmail))
+
+		ctx := context.TODO()
+		err := us.SignIn(ctx, mockUser)
+
+		assert.EqualError(t, err, "Invalid email and password combination")
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		assert.Equal(t, failures+1, testutil.ToFloat64(metrics.SignInFailures.WithLabelValues(metrics.SignInUnknownEmail)))
+		mockUserRepository.AssertCalled(t, "FindByEmail", mock.Anything, unknownEmail)
+	})
+
+	t.Run("Repository failure", func(t *testing.T) {
+		failingEmail := "failing@remember.test"
+
+		mockUser := &model.User{
+			Email:    failingEmail,
+			Password: validPW,
+		}
+
+		mockUserRepository.
+			On("FindByEmail", mock.Anything, failingEmail).Return(nil, apperrors.NewInternal()).Once()
+
+		ctx := context.TODO()
+		err := us.SignIn(ctx, mockUser)
+
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+}
+
+func TestSignInLockout(t *testing.T) {
+	email := "vuluu040320@gmail.com"
+	validPW := "SuperKeyPass123"
+	hashedValidPW, _ := hashPassword(validPW, HashParams{})
+	invalidPW := "WrongPass123"
+
+	policy := LockoutPolicy{
+		Threshold:    3,
+		BaseDuration: time.Minute,
+		MaxDuration:  time.Hour,
+	}
+
+	t.Run("Counts failures below the threshold", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{UserRepository: mockUserRepository, Lockout: policy})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: hashedValidPW, FailedSignIns: 1}, nil)
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(2, nil)
+
+		err := us.SignIn(context.TODO(), &model.User{Email: email, Password: invalidPW})
+
+		assert.EqualError(t, err, invalidCredentials)
+		mockUserRepository.AssertNotCalled(t, "LockUntil", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Locks at the threshold", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{UserRepository: mockUserRepository, Lockout: policy})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: hashedValidPW, FailedSignIns: 2}, nil)
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(3, nil)
+
+		inAMinute := mock.MatchedBy(func(until time.Time) bool {
+			return time.Until(until) > 59*time.Second && time.Until(until) <= time.Minute
+		})
+		mockUserRepository.On("LockUntil", mock.Anything, uid, inAMinute).Return(nil)
+
+		err := us.SignIn(context.TODO(), &model.User{Email: email, Password: invalidPW})
+
+		assert.EqualError(t, err, accountLocked)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Refuses locked accounts even with the right password", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{UserRepository: mockUserRepository, Lockout: policy})
+
+		lockedUntil := time.Now().Add(time.Minute)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: hashedValidPW, FailedSignIns: 3, LockedUntil: &lockedUntil}, nil)
+
+		u := &model.User{Email: email, Password: validPW}
+		err := us.SignIn(context.TODO(), u)
+
+		assert.EqualError(t, err, accountLocked)
+		assert.Equal(t, uuid.Nil, u.UID)
+		mockUserRepository.AssertNotCalled(t, "IncrementFailedSignIns", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Successful sign in resets the counter", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{UserRepository: mockUserRepository, Lockout: policy})
+
+		lockedUntil := time.Now().Add(-time.Second)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: hashedValidPW, FailedSignIns: 3, LockedUntil: &lockedUntil}, nil)
+		mockUserRepository.On("ResetFailedSignIns", mock.Anything, uid).Return(nil)
+
+		u := &model.User{Email: email, Password: validPW}
+		err := us.SignIn(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		assert.Zero(t, u.FailedSignIns)
+		assert.Nil(t, u.LockedUntil)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Two-factor users keep the counter until their code passes", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{UserRepository: mockUserRepository, Lockout: policy})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: hashedValidPW, FailedSignIns: 2, TOTPEnabled: true}, nil)
+
+		u := &model.User{Email: email, Password: validPW}
+		err := us.SignIn(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, u.FailedSignIns)
+		mockUserRepository.AssertNotCalled(t, "ResetFailedSignIns", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSignInRehash(t *testing.T) {
+	email := "vuluu040320@gmail.com"
+	validPW := "SuperKeyPass123"
+
+	bcryptPW, _ := bcrypt.GenerateFromPassword([]byte(validPW), bcrypt.MinCost)
+	weakPW, _ := hashPassword(validPW, HashParams{N: 1024})
+	currentPW, _ := hashPassword(validPW, HashParams{})
+
+	for name, stored := range map[string]string{
+		"Upgrades legacy hex hashes":        legacyHash(t, validPW),
+		"Upgrades imported bcrypt hashes":   string(bcryptPW),
+		"Upgrades hashes with weaker costs": weakPW,
+	} {
+		t.Run(name, func(t *testing.T) {
+			uid, _ := uuid.NewRandom()
+			mockUserRepository := new(mocks.MockUserRepository)
+			us := NewUserService(&USConfig{UserRepository: mockUserRepository})
+
+			var rehashed string
+
+			mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: stored}, nil)
+			mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).
+				Run(func(args mock.Arguments) {
+					rehashed = args.String(2)
+				}).Return(nil)
+
+			u := &model.User{Email: email, Password: validPW}
+			require.NoError(t, us.SignIn(context.TODO(), u))
+
+			assert.Equal(t, rehashed, u.Password)
+			assert.False(t, needsRehash(rehashed, HashParams{}))
+
+			match, err := comparePasswords(rehashed, validPW, HashParams{})
+			assert.NoError(t, err)
+			assert.True(t, match)
+		})
+	}
+
+	t.Run("Keeps current hashes", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{UserRepository: mockUserRepository})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: currentPW}, nil)
+
+		require.NoError(t, us.SignIn(context.TODO(), &model.User{Email: email, Password: validPW}))
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failing to store the rehash still signs in", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{UserRepository: mockUserRepository})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: string(bcryptPW)}, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).Return(apperrors.NewInternal())
+
+		u := &model.User{Email: email, Password: validPW}
+		require.NoError(t, us.SignIn(context.TODO(), u))
+
+		assert.Equal(t, uid, u.UID)
+		assert.Equal(t, string(bcryptPW), u.Password)
+	})
+}
+
+func TestLockoutPolicyDuration(t *testing.T) {
+	policy := LockoutPolicy{
+		Threshold:    3,
+		BaseDuration: time.Minute,
+		MaxDuration:  10 * time.Minute,
+	}
+
+	assert.Zero(t, policy.duration(2))
+	assert.Equal(t, time.Minute, policy.duration(3))
+	assert.Equal(t, 2*time.Minute, policy.duration(4))
+	assert.Equal(t, 8*time.Minute, policy.duration(6))
+	assert.Equal(t, 10*time.Minute, policy.duration(7))
+	assert.Equal(t, 10*time.Minute, policy.duration(100))
+
+	assert.Zero(t, LockoutPolicy{}.duration(100))
+}
+
+// newImageFileHeader parses contents into a multipart.FileHeader
+// the way an uploaded "imageFile" reaches the service
+func newImageFileHeader(t *testing.T, contentType string, contents []byte) *multipart.FileHeader {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="imageFile"; filename="image"`)
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	require.NoError(t, err)
+
+	_, err = part.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1024)
+	require.NoError(t, err)
+
+	return form.File["imageFile"][0]
+}
+
+func TestSetProfileImage(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:      uid,
+			Email:    "vuluu040320@gmail.com",
+			ImageUrl: "/images/previous.png",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		imageFileHeader := newImageFileHeader(t, "image/png", []byte("png bytes"))
+
+		var objName string
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.
+			On("UpdateProfile", mock.Anything, mock.AnythingOfType("string"), "image/png", mock.Anything).
+			Run(func(args mock.Arguments) {
+				objName = args.String(1)
+			}).
+			Return("/images/new.png", nil)
+
+		mockUpdatedUser := &model.User{
+			UID:      uid,
+			Email:    "vuluu040320@gmail.com",
+			ImageUrl: "/images/new.png",
+		}
+
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "/images/new.png").Return(mockUpdatedUser, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "previous.png").Return(nil)
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFileHeader)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUpdatedUser, u)
+		assert.Regexp(t, `^[0-9a-f-]{36}\.png$`, objName)
+		mockUserRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+	})
+
+	t.Run("Storage error", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID: uid,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		imageFileHeader := newImageFileHeader(t, "image/jpeg", []byte("jpeg bytes"))
+
+		mockError := apperrors.NewInternal()
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.
+			On("UpdateProfile", mock.Anything, mock.AnythingOfType("string"), "image/jpeg", mock.Anything).
+			Return(nil, mockError)
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFileHeader)
+
+		assert.Nil(t, u)
+		assert.Equal(t, mockError, err)
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Removes new image when user update fails", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:      uid,
+			ImageUrl: "/images/previous.png",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		imageFileHeader := newImageFileHeader(t, "image/png", []byte("png bytes"))
+
+		var objName string
+
+		mockError := apperrors.NewInternal()
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.
+			On("UpdateProfile", mock.Anything, mock.AnythingOfType("string"), "image/png", mock.Anything).
+			Run(func(args mock.Arguments) {
+				objName = args.String(1)
+			}).
+			Return("/images/new.png", nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "/images/new.png").Return(nil, mockError)
+		mockImageRepository.On("DeleteProfile", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+
+		u, err := us.SetProfileImage(context.TODO(), uid, imageFileHeader)
+
+		assert.Nil(t, u)
+		assert.Equal(t, mockError, err)
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.Anything, objName)
+		// the previous image is kept since the user still points to it
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, "previous.png")
+	})
+}
+
+func TestClearProfileImage(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:      uid,
+			ImageUrl: "/images/avatar.png",
+		}
+
+		mockUpdatedUser := &model.User{
+			UID: uid,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(mockUser, nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "").Return(mockUpdatedUser, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "avatar.png").Return(nil)
+
+		u, err := us.ClearProfileImage(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUpdatedUser, u)
+		mockUserRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+	})
+
+	t.Run("No image is a no-op", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID: uid,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(mockUser, nil)
+
+		u, err := us.ClearProfileImage(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, u)
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Storage error after clearing", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:      uid,
+			ImageUrl: "/images/avatar.png",
+		}
+
+		mockUpdatedUser := &model.User{
+			UID: uid,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(mockUser, nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "").Return(mockUpdatedUser, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "avatar.png").Return(apperrors.NewInternal())
+
+		u, err := us.ClearProfileImage(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUpdatedUser, u)
+	})
+
+	t.Run("UpdateImage error", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:      uid,
+			ImageUrl: "/images/avatar.png",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockError := apperrors.NewInternal()
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(mockUser, nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "").Return(nil, mockError)
+
+		u, err := us.ClearProfileImage(context.TODO(), uid)
+
+		assert.Nil(t, u)
+		assert.Equal(t, mockError, err)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUpdateDetails(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	current := &model.User{
+		UID:      uid,
+		Email:    "vuluu040320@gmail.com",
+		Password: "hashed",
+		Name:     "Vũ Lưu",
+		ImageUrl: "/images/avatar.png",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		currentCopy := *current
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&currentCopy, nil)
+
+		newEmail := "new@remember.test"
+
+		mockUserRepository.On("FindByEmail", mock.Anything, newEmail).Return(nil, apperrors.NewNotFound("email", newEmail))
+
+		expectedUser := *current
+		expectedUser.Email = newEmail
+		expectedUser.Website = "https://remember.test"
+
+		mockUserRepository.On("Update", mock.Anything, &expectedUser).Return(nil)
+
+		u := &model.User{
+			UID:     uid,
+			Name:    current.Name,
+			Email:   newEmail,
+			Website: "https://remember.test",
+		}
+
+		emailChanged, err := us.UpdateDetails(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.True(t, emailChanged)
+		assert.Equal(t, &expectedUser, u)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Nothing changed", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		currentCopy := *current
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&currentCopy, nil)
+
+		u := &model.User{
+			UID:   uid,
+			Name:  current.Name,
+			Email: current.Email,
+		}
+
+		emailChanged, err := us.UpdateDetails(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.False(t, emailChanged)
+		assert.Equal(t, current, u)
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Email unchanged", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		currentCopy := *current
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&currentCopy, nil)
+
+		expectedUser := *current
+		expectedUser.Website = "https://remember.test"
+
+		mockUserRepository.On("Update", mock.Anything, &expectedUser).Return(nil)
+
+		u := &model.User{
+			UID:     uid,
+			Name:    current.Name,
+			Email:   current.Email,
+			Website: "https://remember.test",
+		}
+
+		emailChanged, err := us.UpdateDetails(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.False(t, emailChanged)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Email belongs to someone else", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		currentCopy := *current
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&currentCopy, nil)
+
+		takenEmail := "taken@remember.test"
+		otherUID, _ := uuid.NewRandom()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, takenEmail).Return(&model.User{UID: otherUID, Email: takenEmail}, nil)
+
+		u := &model.User{
+			UID:   uid,
+			Email: takenEmail,
+		}
+
+		_, err := us.UpdateDetails(context.TODO(), u)
+
+		assert.Equal(t, apperrors.NewConflict("email", takenEmail), err)
+		mockUserRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repository conflict", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		currentCopy := *current
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&currentCopy, nil)
+
+		raceEmail := "race@remember.test"
+		mockError := apperrors.NewConflict("email", raceEmail)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, raceEmail).Return(nil, apperrors.NewNotFound("email", raceEmail))
+		mockUserRepository.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).Return(mockError)
+
+		u := &model.User{
+			UID:   uid,
+			Email: raceEmail,
+		}
+
+		_, err := us.UpdateDetails(context.TODO(), u)
+
+		assert.Equal(t, mockError, err)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	currentPW := "currentpassword"
+	hashedCurrentPW, _ := hashPassword(currentPW, HashParams{})
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		var newHash string
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedCurrentPW, FailedSignIns: 2}, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				newHash = args.String(2)
+			}).Return(nil)
+
+		u, err := us.ChangePassword(context.TODO(), uid, currentPW, "newpassword")
+		require.NoError(t, err)
+
+		assert.Equal(t, newHash, u.Password)
+		assert.Equal(t, 0, u.FailedSignIns)
+
+		match, err := comparePasswords(newHash, "newpassword", HashParams{})
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			Lockout:        LockoutPolicy{Threshold: 3, BaseDuration: time.Minute, MaxDuration: time.Hour},
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedCurrentPW}, nil)
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(1, nil)
+
+		u, err := us.ChangePassword(context.TODO(), uid, "wrongpassword", "newpassword")
+		assert.Nil(t, u)
+		assert.EqualError(t, err, "Current password is incorrect")
+		assert.Equal(t, apperrors.Status(err), apperrors.NewAuthorization("").Status())
+
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "LockUntil", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Wrong current password reaching the lockout threshold", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			Lockout:        LockoutPolicy{Threshold: 3, BaseDuration: time.Minute, MaxDuration: time.Hour},
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedCurrentPW, FailedSignIns: 2}, nil)
+		mockUserRepository.On("IncrementFailedSignIns", mock.Anything, uid).Return(3, nil)
+		mockUserRepository.On("LockUntil", mock.Anything, uid, mock.AnythingOfType("time.Time")).Return(nil)
+
+		u, err := us.ChangePassword(context.TODO(), uid, "wrongpassword", "newpassword")
+		assert.Nil(t, u)
+		assert.EqualError(t, err, accountLocked)
+
+		mockUserRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Locked account", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			Lockout:        LockoutPolicy{Threshold: 3, BaseDuration: time.Minute, MaxDuration: time.Hour},
+		})
+
+		lockedUntil := time.Now().Add(time.Minute)
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedCurrentPW, FailedSignIns: 3, LockedUntil: &lockedUntil}, nil)
+
+		// even the right password is refused
+		u, err := us.ChangePassword(context.TODO(), uid, currentPW, "newpassword")
+		assert.Nil(t, u)
+		assert.EqualError(t, err, accountLocked)
+
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "IncrementFailedSignIns", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindById", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedCurrentPW}, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).Return(apperrors.NewInternal())
+
+		u, err := us.ChangePassword(context.TODO(), uid, currentPW, "newpassword")
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+}
@@ -2,22 +2,103 @@ package service
 
 import (
 	"context"
+	"mime/multipart"
+	"net/http"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/metrics"
 	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
 )
 
+// invalidCredentials is returned for both unknown emails and wrong
+// passwords so that accounts cannot be enumerated
+const invalidCredentials = "Invalid email and password combination"
+
+// accountLocked is returned while sign ins to an account are refused
+const accountLocked = "Account is locked after too many failed sign in attempts. Try again later"
+
+// LockoutPolicy locks an account once Threshold consecutive sign ins
+// failed, for BaseDuration doubling with every further failure up to
+// MaxDuration. A zero Threshold disables lockout
+type LockoutPolicy struct {
+	Threshold    int
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+// duration is how long an account is locked after failures
+// consecutive failed sign ins, zero when it is not locked
+func (p LockoutPolicy) duration(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+
+	d := p.BaseDuration
+
+	for i := p.Threshold; i < failures && d < p.MaxDuration; i++ {
+		d *= 2
+	}
+
+	if d > p.MaxDuration {
+		return p.MaxDuration
+	}
+
+	return d
+}
+
+// recordFailure counts a failed sign in of u, locking the account
+// once the threshold is reached. It reports whether u is now locked
+func (p LockoutPolicy) recordFailure(ctx context.Context, repo model.UserRepository, u *model.User) (bool, error) {
+	if p.Threshold <= 0 {
+		return false, nil
+	}
+
+	failures, err := repo.IncrementFailedSignIns(ctx, u.UID)
+
+	if err != nil {
+		return false, err
+	}
+
+	d := p.duration(failures)
+
+	if d == 0 {
+		return false, nil
+	}
+
+	if err := repo.LockUntil(ctx, u.UID, time.Now().Add(d)); err != nil {
+		return false, err
+	}
+
+	logging.FromContext(ctx).Warn("Locked account after failed sign ins", "uid", u.UID, "failures", failures, "duration", d)
+
+	return true, nil
+}
+
 type UserService struct {
-	UserRepository model.UserRepository
+	UserRepository  model.UserRepository
+	ImageRepository model.ImageRepository
+	Lockout         LockoutPolicy
+	Hash            HashParams
 }
 
 type USConfig struct {
-	UserRepository model.UserRepository
+	UserRepository  model.UserRepository
+	ImageRepository model.ImageRepository
+	Lockout         LockoutPolicy
+	// Hash is what passwords are hashed with. Weaker stored hashes are
+	// upgraded on sign in
+	Hash HashParams
 }
 
 func NewUserService(c *USConfig) model.UserService {
 	return &UserService{
-		UserRepository: c.UserRepository,
+		UserRepository:  c.UserRepository,
+		ImageRepository: c.ImageRepository,
+		Lockout:         c.Lockout,
+		Hash:            c.Hash,
 	}
 }
 
@@ -28,5 +109,305 @@ func (s *UserService) Get(ctx context.Context, uid uuid.UUID) (*model.User, erro
 }
 
 func (s *UserService) SignUp(ctx context.Context, u *model.User) error {
-	panic("Method not implemented")
+	pw, err := hashPassword(u.Password, s.Hash)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to hash password", "email", u.Email, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	u.Password = pw
+
+	if err := s.UserRepository.Create(ctx, u); err != nil {
+		return err
+	}
+
+	metrics.SignUps.Inc()
+
+	return nil
+}
+
+// SignIn reaches out to a UserRepository to check if the user exists
+// and then compares the supplied password with the provided password
+// if a valid email/password combo is provided, u will hold all
+// available user fields. Consecutive wrong passwords lock the account
+// according to the Lockout policy. Passwords stored with another
+// algorithm or weaker costs than Hash are rehashed
+func (s *UserService) SignIn(ctx context.Context, u *model.User) error {
+	uFetched, err := s.UserRepository.FindByEmail(ctx, u.Email)
+
+	// Will return NotAuthorized to client to omit details of why
+	if err != nil {
+		if apperrors.Status(err) != http.StatusNotFound {
+			metrics.SignInFailures.WithLabelValues(metrics.SignInError).Inc()
+			return err
+		}
+
+		metrics.SignInFailures.WithLabelValues(metrics.SignInUnknownEmail).Inc()
+
+		// spend the same time as a real comparison so response times
+		// don't reveal which emails are registered
+		comparePasswords(dummyPasswordHash(s.Hash), u.Password, s.Hash)
+
+		return apperrors.NewAuthorization(invalidCredentials)
+	}
+
+	if uFetched.IsLocked(time.Now()) {
+		metrics.SignInFailures.WithLabelValues(metrics.SignInLocked).Inc()
+		return apperrors.NewAuthorization(accountLocked)
+	}
+
+	match, err := comparePasswords(uFetched.Password, u.Password, s.Hash)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to compare password", "uid", uFetched.UID, "err", err)
+		metrics.SignInFailures.WithLabelValues(metrics.SignInError).Inc()
+		return apperrors.NewInternal()
+	}
+
+	if !match {
+		metrics.SignInFailures.WithLabelValues(metrics.SignInWrongPassword).Inc()
+		return s.failSignIn(ctx, uFetched)
+	}
+
+	if needsRehash(uFetched.Password, s.Hash) {
+		s.rehash(ctx, uFetched, u.Password)
+	}
+
+	// with two-factor authentication the counter is only reset once
+	// the second factor passed, so wrong codes add up across sign ins
+	if !uFetched.TOTPEnabled && (uFetched.FailedSignIns > 0 || uFetched.LockedUntil != nil) {
+		if err := s.UserRepository.ResetFailedSignIns(ctx, uFetched.UID); err != nil {
+			return err
+		}
+
+		uFetched.FailedSignIns = 0
+		uFetched.LockedUntil = nil
+	}
+
+	*u = *uFetched
+	return nil
+}
+
+// failSignIn counts a wrong password for u, locking the account once
+// the Lockout threshold is reached
+func (s *UserService) failSignIn(ctx context.Context, u *model.User) error {
+	locked, err := s.Lockout.recordFailure(ctx, s.UserRepository, u)
+
+	if err != nil {
+		return err
+	}
+
+	if locked {
+		return apperrors.NewAuthorization(accountLocked)
+	}
+
+	return apperrors.NewAuthorization(invalidCredentials)
+}
+
+// ChangePassword checks currentPassword like SignIn does. Wrong ones
+// count towards the same lockout, so a stolen session can't be used to
+// guess the password
+func (s *UserService) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) (*model.User, error) {
+	u, err := s.UserRepository.FindById(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.IsLocked(time.Now()) {
+		return nil, apperrors.NewAuthorization(accountLocked)
+	}
+
+	match, err := comparePasswords(u.Password, currentPassword, s.Hash)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to compare password", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if !match {
+		locked, err := s.Lockout.recordFailure(ctx, s.UserRepository, u)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if locked {
+			return nil, apperrors.NewAuthorization(accountLocked)
+		}
+
+		return nil, apperrors.NewAuthorization("Current password is incorrect")
+	}
+
+	pw, err := hashPassword(newPassword, s.Hash)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to hash password", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, uid, pw); err != nil {
+		return nil, err
+	}
+
+	u.Password = pw
+	u.FailedSignIns = 0
+	u.LockedUntil = nil
+
+	return u, nil
+}
+
+// rehash replaces the stored hash of u with one of password made with
+// Hash. Failing to leaves the old hash in place without failing the
+// sign in, it is tried again next time
+func (s *UserService) rehash(ctx context.Context, u *model.User, password string) {
+	pw, err := hashPassword(password, s.Hash)
+
+	if err != nil {
+		logging.FromContext(ctx).Warn("Unable to rehash password", "uid", u.UID, "err", err)
+		return
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, u.UID, pw); err != nil {
+		logging.FromContext(ctx).Warn("Unable to store rehashed password", "uid", u.UID, "err", err)
+		return
+	}
+
+	logging.FromContext(ctx).Info("Upgraded password hash", "uid", u.UID)
+
+	// UpdatePassword lifted any lockout too
+	u.Password = pw
+	u.FailedSignIns = 0
+	u.LockedUntil = nil
+}
+
+// UpdateDetails updates the name, email and website of the user
+// with u.UID, writing to the repository only if any of them changed
+// On success u holds all of the user's fields, and whether the stored
+// email changed, leaving it unverified, is reported
+func (s *UserService) UpdateDetails(ctx context.Context, u *model.User) (bool, error) {
+	current, err := s.UserRepository.FindById(ctx, u.UID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if u.Email != current.Email {
+		existing, err := s.UserRepository.FindByEmail(ctx, u.Email)
+
+		if err == nil && existing.UID != u.UID {
+			return false, apperrors.NewConflict("email", u.Email)
+		}
+
+		if err != nil && apperrors.Status(err) != http.StatusNotFound {
+			return false, err
+		}
+	}
+
+	if u.Name == current.Name && u.Email == current.Email && u.Website == current.Website {
+		*u = *current
+		return false, nil
+	}
+
+	updated := *current
+	updated.Name = u.Name
+	updated.Email = u.Email
+	updated.Website = u.Website
+
+	// the repository still reports a conflict if the email
+	// was taken since it was checked above
+	if err := s.UserRepository.Update(ctx, &updated); err != nil {
+		return false, err
+	}
+
+	*u = updated
+	return updated.Email != current.Email, nil
+}
+
+// SetProfileImage stores the uploaded image, points the user's ImageUrl
+// at it and then removes the image it replaces. The image's
+// Content-Type header is expected to be verified by the caller
+func (s *UserService) SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*model.User, error) {
+	u, err := s.UserRepository.FindById(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := imageFileHeader.Header.Get("Content-Type")
+	objName, err := newImageObjName(contentType)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to create image object name", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	imageFile, err := imageFileHeader.Open()
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to open image file", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	defer imageFile.Close()
+
+	imageURL, err := s.ImageRepository.UpdateProfile(ctx, objName, contentType, imageFile)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to upload image to storage provider", "err", err)
+		return nil, err
+	}
+
+	updatedUser, err := s.UserRepository.UpdateImage(ctx, u.UID, imageURL)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to update imageURL", "uid", uid, "err", err)
+
+		// don't leave an image behind that no user points to
+		if err := s.ImageRepository.DeleteProfile(ctx, objName); err != nil {
+			logging.FromContext(ctx).Warn("Unable to delete orphaned image", "object", objName, "err", err)
+		}
+
+		return nil, err
+	}
+
+	if u.ImageUrl != "" {
+		if err := s.ImageRepository.DeleteProfile(ctx, imageObjName(u.ImageUrl)); err != nil {
+			logging.FromContext(ctx).Warn("Unable to delete previous image", "uid", uid, "err", err)
+		}
+	}
+
+	return updatedUser, nil
+}
+
+// ClearProfileImage removes the user's profile image. Users without
+// an image are returned unchanged. The stored image is deleted only
+// after ImageUrl is cleared, so a storage failure leaves an unused
+// object behind rather than a user pointing to a missing image
+func (s *UserService) ClearProfileImage(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	u, err := s.UserRepository.FindById(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.ImageUrl == "" {
+		return u, nil
+	}
+
+	updatedUser, err := s.UserRepository.UpdateImage(ctx, uid, "")
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to clear imageURL", "uid", uid, "err", err)
+		return nil, err
+	}
+
+	objName := imageObjName(u.ImageUrl)
+
+	if err := s.ImageRepository.DeleteProfile(ctx, objName); err != nil {
+		logging.FromContext(ctx).Warn("Unable to delete image", "object", objName, "uid", uid, "err", err)
+	}
+
+	return updatedUser, nil
 }
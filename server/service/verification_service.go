@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/vuluu2k/remember_fullstack/server/logging"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+)
+
+// invalidVerificationToken covers bad signatures, expired tokens and
+// tokens sent to an email the user has since changed
+const invalidVerificationToken = "Verification token is invalid or has expired"
+
+// VerificationService emails users signed, single use links which
+// verify their email address
+type VerificationService struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	Mailer          model.Mailer
+	Secret          string
+	ExpirationSecs  int64
+	// VerifyURL is where links point, with the token in the token
+	// query parameter
+	VerifyURL string
+}
+
+type VSConfig struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	Mailer          model.Mailer
+	Secret          string
+	ExpirationSecs  int64
+	VerifyURL       string
+}
+
+func NewVerificationService(c *VSConfig) model.VerificationService {
+	return &VerificationService{
+		UserRepository:  c.UserRepository,
+		TokenRepository: c.TokenRepository,
+		Mailer:          c.Mailer,
+		Secret:          c.Secret,
+		ExpirationSecs:  c.ExpirationSecs,
+		VerifyURL:       c.VerifyURL,
+	}
+}
+
+func (s *VerificationService) SendVerification(ctx context.Context, u *model.User) error {
+	if u.EmailVerified {
+		return nil
+	}
+
+	token, err := generateVerificationToken(u, s.Secret, s.ExpirationSecs)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Error generating verification token", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.TokenRepository.SetVerificationToken(ctx, u.UID.String(), token.ID.String(), token.ExpiresIn); err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.VerifyURL)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("Invalid verification URL", "url", s.VerifyURL, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	query := link.Query()
+	query.Set("token", token.SS)
+	link.RawQuery = query.Encode()
+
+	return s.Mailer.Send(ctx, &model.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Welcome to Remember!\n\nConfirm that %s is your email address by opening the link below within %v:\n\n%s\n\nIf you didn't sign up, you can ignore this email.\n",
+			u.Email, humanDuration(token.ExpiresIn), link.String()),
+	})
+}
+
+func (s *VerificationService) VerifyEmail(ctx context.Context, tokenString string) (*model.User, error) {
+	claims, err := validateVerificationToken(tokenString, s.Secret)
+
+	if err != nil {
+		logging.FromContext(ctx).Info("Unable to validate or parse verification token", "err", err)
+		return nil, apperrors.NewAuthorization(invalidVerificationToken)
+	}
+
+	if err := s.TokenRepository.DeleteVerificationToken(ctx, claims.UID.String(), claims.ID); err != nil {
+		if apperrors.Status(err) == http.StatusUnauthorized {
+			return nil, apperrors.NewAuthorization("Verification token has already been used")
+		}
+
+		return nil, err
+	}
+
+	u, err := s.UserRepository.VerifyEmail(ctx, claims.UID, claims.Email)
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, apperrors.NewAuthorization(invalidVerificationToken)
+		}
+
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// humanDuration renders d in whole hours, or minutes when shorter
+func humanDuration(d time.Duration) string {
+	n, unit := int64(d/time.Minute), "minute"
+
+	if d >= time.Hour {
+		n, unit = int64(d/time.Hour), "hour"
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+
+	return fmt.Sprintf("%d %s", n, unit)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vuluu2k/remember_fullstack/server/mailer"
+	"github.com/vuluu2k/remember_fullstack/server/model"
+	"github.com/vuluu2k/remember_fullstack/server/model/apperrors"
+	"github.com/vuluu2k/remember_fullstack/server/model/mocks"
+)
+
+var linkRegexp = regexp.MustCompile(`https?://\S+`)
+
+// sentToken extracts the token from the link in the last email sent
+func sentToken(t *testing.T, m *mailer.MemoryMailer) string {
+	t.Helper()
+
+	messages := m.Messages()
+	require.NotEmpty(t, messages)
+
+	link, err := url.Parse(linkRegexp.FindString(messages[len(messages)-1].Body))
+	require.NoError(t, err)
+
+	return link.Query().Get("token")
+}
+
+func TestVerificationService(t *testing.T) {
+	secret := "anotsorandomverificationsecret"
+	uid, _ := uuid.NewRandom()
+
+	u := &model.User{
+		UID:   uid,
+		Email: "vuluu040320@gmail.com",
+	}
+
+	t.Run("Sends a link and verifies it once", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		vs := NewVerificationService(&VSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			Secret:          secret,
+			ExpirationSecs:  3600,
+			VerifyURL:       "http://dev2000.test/api/account/verify-email",
+		})
+
+		var tokenID string
+
+		mockTokenRepository.On("SetVerificationToken", mock.Anything, uid.String(), mock.AnythingOfType("string"), time.Hour).
+			Run(func(args mock.Arguments) {
+				tokenID = args.String(2)
+			}).Return(nil)
+
+		require.NoError(t, vs.SendVerification(context.TODO(), u))
+
+		messages := memoryMailer.Messages()
+		require.Len(t, messages, 1)
+		assert.Equal(t, u.Email, messages[0].To)
+		assert.Contains(t, messages[0].Body, "http://dev2000.test/api/account/verify-email?token=")
+		assert.Contains(t, messages[0].Body, "within 1 hour:")
+
+		token := sentToken(t, memoryMailer)
+
+		verified := &model.User{UID: uid, Email: u.Email, EmailVerified: true}
+		mockTokenRepository.On("DeleteVerificationToken", mock.Anything, uid.String(), tokenID).Return(nil).Once()
+		mockUserRepository.On("VerifyEmail", mock.Anything, uid, u.Email).Return(verified, nil)
+
+		res, err := vs.VerifyEmail(context.TODO(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, verified, res)
+
+		mockTokenRepository.On("DeleteVerificationToken", mock.Anything, uid.String(), tokenID).Return(apperrors.NewAuthorization("Invalid verification token"))
+
+		_, err = vs.VerifyEmail(context.TODO(), token)
+		assert.EqualError(t, err, "Verification token has already been used")
+	})
+
+	t.Run("Does not email verified users", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		vs := NewVerificationService(&VSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			Secret:          secret,
+			ExpirationSecs:  3600,
+			VerifyURL:       "http://dev2000.test/api/account/verify-email",
+		})
+
+		err := vs.SendVerification(context.TODO(), &model.User{UID: uid, Email: u.Email, EmailVerified: true})
+
+		assert.NoError(t, err)
+		assert.Empty(t, memoryMailer.Messages())
+		mockTokenRepository.AssertNotCalled(t, "SetVerificationToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects tokens signed with another secret or for another purpose", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		vs := NewVerificationService(&VSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			Secret:          secret,
+			ExpirationSecs:  3600,
+			VerifyURL:       "http://dev2000.test/api/account/verify-email",
+		})
+
+		forged, err := generateVerificationToken(u, "anothersecret", 3600)
+		require.NoError(t, err)
+
+		_, err = vs.VerifyEmail(context.TODO(), forged.SS)
+		assert.EqualError(t, err, invalidVerificationToken)
+
+		// refresh tokens are HS256 signed too, but lack the audience
+		refreshToken, err := generateRefreshToken(uid, secret, 3600)
+		require.NoError(t, err)
+
+		_, err = vs.VerifyEmail(context.TODO(), refreshToken.SS)
+		assert.EqualError(t, err, invalidVerificationToken)
+
+		mockTokenRepository.AssertNotCalled(t, "DeleteVerificationToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects tokens for a changed email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		memoryMailer := mailer.NewMemoryMailer()
+
+		vs := NewVerificationService(&VSConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          memoryMailer,
+			Secret:          secret,
+			ExpirationSecs:  3600,
+			VerifyURL:       "http://dev2000.test/api/account/verify-email",
+		})
+
+		token, err := generateVerificationToken(u, secret, 3600)
+		require.NoError(t, err)
+
+		mockTokenRepository.On("DeleteVerificationToken", mock.Anything, uid.String(), token.ID.String()).Return(nil)
+		mockUserRepository.On("VerifyEmail", mock.Anything, uid, u.Email).Return(nil, apperrors.NewNotFound("email", u.Email))
+
+		_, err = vs.VerifyEmail(context.TODO(), token.SS)
+		assert.EqualError(t, err, invalidVerificationToken)
+	})
+}